@@ -0,0 +1,435 @@
+package minicache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+//日志中的操作类型
+type aofOp uint8
+
+const (
+	aofSet    aofOp = iota + 1 //写入数据项
+	aofDelete                  //删除键
+	aofExpire                  //修改过期时间
+	aofFlush                   //清空缓存
+)
+
+//一条操作记录,过期时间为绝对时间
+type aofRecord struct {
+	Op   aofOp
+	Key  string
+	Item Item
+}
+
+//追加写入的操作日志,记录在缓存写锁下按发生顺序追加
+type aofLog struct {
+	mtx     sync.Mutex
+	f       *os.File
+	w       *bufio.Writer
+	buf     bytes.Buffer
+	always  bool //每条记录都同步到磁盘
+	onError func(error)
+	size    int64 //日志文件的当前大小
+	//重写相关
+	rewriteMin int64 //日志超过该大小才重写,0表示不自动重写
+	rewritten  int64 //上次重写后的大小
+	rewriting  bool
+	scheduled  bool         //已启动后台重写
+	rewriteBuf bytes.Buffer //重写期间追加的记录,重写结束时写入新日志
+}
+
+//开启追加日志:写入、删除、修改过期时间和清空操作追加到fileName,启动时可用ReplayAOF回放
+//每隔syncInterval将日志同步到磁盘,syncInterval不大于0时每条记录都同步;日志的写入错误传给onError(可以为nil)
+//每条记录单独用gob编码并带长度前缀,崩溃时最后一条不完整的记录在回放时被忽略
+func WithAOF(fileName string, syncInterval time.Duration, onError func(error)) Option {
+	return func(minic *Minicache) {
+		minic.aofFile = fileName
+		minic.aofSyncInterval = syncInterval
+		minic.onAOFError = onError
+	}
+}
+
+//打开日志文件并启动定时同步
+func (minic *minicache) openAOF() {
+	f, err := os.OpenFile(minic.aofFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		if minic.onAOFError != nil {
+			minic.onAOFError(err)
+		}
+		return
+	}
+	var size int64
+	if fi, err := f.Stat(); err == nil {
+		size = fi.Size()
+	}
+	minic.aof = &aofLog{
+		f:          f,
+		w:          bufio.NewWriter(f),
+		always:     minic.aofSyncInterval <= 0,
+		onError:    minic.onAOFError,
+		size:       size,
+		rewriteMin: minic.aofRewriteMin,
+		rewritten:  size,
+	}
+	if !minic.aof.always {
+		minic.background.Add(1)
+		go minic.aofSyncLoop()
+	}
+}
+
+func (minic *minicache) aofSyncLoop() {
+	defer minic.background.Done()
+	ticker := time.NewTicker(minic.aofSyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			minic.aof.sync()
+		case <-minic.done:
+			return
+		}
+	}
+}
+
+//记录一次修改,无锁(调用方持有缓存写锁)
+func (minic *minicache) logOp(op aofOp, k string, item Item) {
+	if minic.aof == nil || minic.aofPaused {
+		return
+	}
+	if minic.aof.append(aofRecord{Op: op, Key: k, Item: Item{
+		Object:     item.Object,
+		Expiration: item.Expiration,
+		Source:     item.Source,
+		Sliding:    item.Sliding,
+	}}) {
+		minic.background.Add(1)
+		go func() {
+			defer minic.background.Done()
+			if err := minic.RewriteAOF(); err != nil && !errors.Is(err, errAOFRewriting) {
+				minic.aof.fail(err)
+			}
+		}()
+	}
+}
+
+func (a *aofLog) fail(err error) {
+	if a.onError != nil {
+		a.onError(err)
+	}
+}
+
+//追加一条记录,日志需要重写时返回true
+func (a *aofLog) append(rec aofRecord) bool {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	if a.f == nil {
+		return false
+	}
+	a.buf.Reset()
+	if err := encodeAOFRecord(&a.buf, rec); err != nil {
+		a.fail(fmt.Errorf("Error encoding AOF record for %s: %v", rec.Key, err))
+		return false
+	}
+	var n [4]byte
+	binary.LittleEndian.PutUint32(n[:], uint32(a.buf.Len()))
+	a.w.Write(n[:])
+	if _, err := a.w.Write(a.buf.Bytes()); err != nil {
+		a.fail(err)
+		return false
+	}
+	a.size += int64(len(n) + a.buf.Len())
+	if a.rewriting {
+		a.rewriteBuf.Write(n[:])
+		a.rewriteBuf.Write(a.buf.Bytes())
+	}
+	if a.always {
+		a.flushLocked()
+	}
+	if a.rewriteMin > 0 && !a.rewriting && !a.scheduled && a.size > a.rewriteMin && a.size > 2*a.rewritten {
+		a.scheduled = true
+		return true
+	}
+	return false
+}
+
+//写入一条带长度前缀的记录,返回写入的字节数
+func writeAOFFrame(w io.Writer, buf *bytes.Buffer, rec aofRecord) (int64, error) {
+	buf.Reset()
+	if err := encodeAOFRecord(buf, rec); err != nil {
+		return 0, fmt.Errorf("Error encoding AOF record for %s: %v", rec.Key, err)
+	}
+	var n [4]byte
+	binary.LittleEndian.PutUint32(n[:], uint32(buf.Len()))
+	if _, err := w.Write(n[:]); err != nil {
+		return 0, err
+	}
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return int64(len(n) + buf.Len()), nil
+}
+
+func encodeAOFRecord(w io.Writer, rec aofRecord) (err error) {
+	defer func() {
+		if x := recover(); x != nil {
+			err = fmt.Errorf("Error registering item types with gob library")
+		}
+	}()
+	if rec.Item.Object != nil {
+		gob.Register(rec.Item.Object)
+	}
+	return gob.NewEncoder(w).Encode(&rec)
+}
+
+func (a *aofLog) flushLocked() error {
+	if err := a.w.Flush(); err != nil {
+		a.fail(err)
+		return err
+	}
+	if err := a.f.Sync(); err != nil {
+		a.fail(err)
+		return err
+	}
+	return nil
+}
+
+//将缓冲的记录写入并同步到磁盘
+func (a *aofLog) sync() {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	if a.f != nil {
+		a.flushLocked()
+	}
+}
+
+func (a *aofLog) close() error {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	if a.f == nil {
+		return nil
+	}
+	err := errors.Join(a.flushLocked(), a.f.Close())
+	a.f = nil
+	return err
+}
+
+//读取一条记录并返回读取的字节数,文件末尾不完整的记录视为结束,返回io.ErrUnexpectedEOF
+func readAOFRecord(r *bufio.Reader) (aofRecord, int64, error) {
+	var rec aofRecord
+	var n [4]byte
+	if m, err := io.ReadFull(r, n[:]); err != nil {
+		if m == 0 {
+			return rec, 0, io.EOF
+		}
+		return rec, 0, io.ErrUnexpectedEOF
+	}
+	b := make([]byte, binary.LittleEndian.Uint32(n[:]))
+	if _, err := io.ReadFull(r, b); err != nil {
+		return rec, 0, io.ErrUnexpectedEOF
+	}
+	err := gob.NewDecoder(bytes.NewReader(b)).Decode(&rec)
+	return rec, int64(len(n) + len(b)), err
+}
+
+//回放追加日志,把缓存恢复到日志记录的状态,回放的操作不会再次写入日志
+//回放按批次加锁,已过期的数据项不会写入;文件不存在时返回nil
+//末尾不完整的记录(写入时崩溃)被截掉,之后追加的记录才能正常回放,因此应在开始写入缓存之前调用
+func (minic *minicache) ReplayAOF(fileName string) error {
+	if closed, err := minic.closed(); closed {
+		return err
+	}
+	return replayAOF(fileName, max(minic.loadBatchSize, 1), func(recs []aofRecord) {
+		minic.rwmtx.Lock()
+		minic.aofPaused = true
+		for _, rec := range recs {
+			minic.applyAOF(rec)
+		}
+		minic.aofPaused = false
+		minic.unlock()
+	})
+}
+
+//按每批至多batch条读取日志并交给apply,截掉末尾不完整的记录,文件不存在时返回nil
+func replayAOF(fileName string, batch int, apply func(recs []aofRecord)) error {
+	f, err := os.Open(fileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	var offset int64
+	for {
+		recs := make([]aofRecord, 0, batch)
+		truncated := false
+		for len(recs) < batch {
+			rec, n, err := readAOFRecord(r)
+			if err == io.EOF {
+				break
+			}
+			if err == io.ErrUnexpectedEOF {
+				truncated = true
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("Error decoding AOF record: %v", err)
+			}
+			offset += n
+			recs = append(recs, rec)
+		}
+		if truncated {
+			if err := os.Truncate(fileName, offset); err != nil {
+				return err
+			}
+		}
+		if len(recs) == 0 {
+			return nil
+		}
+		apply(recs)
+	}
+}
+
+//执行一条日志记录,无锁
+func (minic *minicache) applyAOF(rec aofRecord) {
+	k, item := rec.Key, rec.Item
+	switch rec.Op {
+	case aofSet:
+		if item.IsExpired() {
+			minic.delete(k, EventDelete)
+			return
+		}
+		minic.restore(k, item)
+	case aofDelete:
+		minic.delete(k, EventDelete)
+	case aofExpire:
+		if cur, found := minic.items.get(k); found {
+			cur.Expiration = item.Expiration
+			cur.Sliding = item.Sliding
+			minic.storeItem(k, cur)
+			minic.schedule(k, cur.Expiration)
+		}
+	case aofFlush:
+		minic.flush()
+	}
+}
+
+//已有重写在进行
+var errAOFRewriting = errors.New("AOF rewrite already in progress")
+
+//日志超过minSize且比上次重写后的大小翻倍时在后台重写,把日志压缩为当前数据的写入记录
+func WithAOFRewrite(minSize int64) Option {
+	return func(minic *Minicache) {
+		minic.aofRewriteMin = minSize
+	}
+}
+
+//重写追加日志:把当前未过期的数据项写入同一目录下的新日志,加上重写期间新追加的记录后原子地替换旧日志
+//只在复制数据项时短暂持有读锁,未开启WithAOF时返回nil
+func (minic *minicache) RewriteAOF() error {
+	a := minic.aof
+	if a == nil {
+		return nil
+	}
+	//持有读锁时没有写操作在追加日志,复制的数据和之后追加的记录正好衔接
+	minic.rwmtx.RLock()
+	a.mtx.Lock()
+	a.scheduled = false
+	if a.f == nil || a.rewriting {
+		rewriting := a.rewriting
+		a.mtx.Unlock()
+		minic.rwmtx.RUnlock()
+		if rewriting {
+			return errAOFRewriting
+		}
+		return nil
+	}
+	a.rewriting = true
+	a.rewriteBuf.Reset()
+	a.mtx.Unlock()
+	items := make(map[string]Item, minic.items.len())
+	minic.items.each(func(k string, v Item) bool {
+		if !v.IsExpired() {
+			items[k] = v
+		}
+		return true
+	})
+	minic.rwmtx.RUnlock()
+
+	err := a.rewrite(minic.aofFile, items)
+	if err != nil {
+		a.mtx.Lock()
+		a.rewriting = false
+		a.rewriteBuf.Reset()
+		a.mtx.Unlock()
+	}
+	return err
+}
+
+func (a *aofLog) rewrite(fileName string, items map[string]Item) error {
+	f, err := os.CreateTemp(filepath.Dir(fileName), "."+filepath.Base(fileName)+".rewrite-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	fail := func(err error) error {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	w := bufio.NewWriter(f)
+	var buf bytes.Buffer
+	var size int64
+	for k, item := range items {
+		n, err := writeAOFFrame(w, &buf, aofRecord{Op: aofSet, Key: k, Item: Item{
+			Object:     item.Object,
+			Expiration: item.Expiration,
+			Source:     item.Source,
+			Sliding:    item.Sliding,
+		}})
+		if err != nil {
+			return fail(err)
+		}
+		size += n
+	}
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	if a.f == nil {
+		return fail(nil)
+	}
+	//重写期间追加的记录
+	if _, err := w.Write(a.rewriteBuf.Bytes()); err != nil {
+		return fail(err)
+	}
+	size += int64(a.rewriteBuf.Len())
+	if err := w.Flush(); err != nil {
+		return fail(err)
+	}
+	if err := f.Sync(); err != nil {
+		return fail(err)
+	}
+	if err := a.w.Flush(); err != nil {
+		return fail(err)
+	}
+	if err := os.Rename(tmp, fileName); err != nil {
+		return fail(err)
+	}
+	syncDir(filepath.Dir(fileName))
+	a.f.Close()
+	a.f, a.w = f, bufio.NewWriter(f)
+	a.size, a.rewritten = size, size
+	a.rewriting = false
+	a.rewriteBuf.Reset()
+	return nil
+}
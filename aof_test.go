@@ -0,0 +1,130 @@
+package minicache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAOFReplay(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "cache.aof")
+	c := NewMiniCache(0, 0, WithAOF(f, 0, func(err error) { t.Error(err) }))
+	c.Set("a", 1, 0)
+	c.Set("b", 2, 0)
+	c.Set("c", 3, time.Hour)
+	c.Set("gone", 4, time.Millisecond)
+	c.Delete("b")
+	c.Expire("c", 2*time.Hour)
+	c.Set("a", 5, 0)
+	c.Close()
+	time.Sleep(5 * time.Millisecond)
+
+	r := NewMiniCache(0, 0)
+	defer r.Close()
+	if err := r.ReplayAOF(f); err != nil {
+		t.Fatal(err)
+	}
+	if v, found := r.Get("a"); !found || v != 5 {
+		t.Fatalf(`Get("a") = %v, %v, want 5`, v, found)
+	}
+	if _, found := r.Get("b"); found {
+		t.Fatal("deleted key b was replayed")
+	}
+	if _, found := r.Get("gone"); found {
+		t.Fatal("expired key was replayed")
+	}
+	if ttl, found := r.TTL("c"); !found || ttl <= time.Hour {
+		t.Fatalf(`TTL("c") = %v, %v, want about 2h`, ttl, found)
+	}
+}
+
+func TestAOFReplayFlush(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "cache.aof")
+	c := NewMiniCache(0, 0, WithAOF(f, 0, nil))
+	c.Set("a", 1, 0)
+	c.Flush()
+	c.Set("b", 2, 0)
+	c.Close()
+
+	r := NewMiniCache(0, 0)
+	defer r.Close()
+	if err := r.ReplayAOF(f); err != nil {
+		t.Fatal(err)
+	}
+	if n := r.Count(); n != 1 {
+		t.Fatalf("Count() = %d, want 1", n)
+	}
+}
+
+func TestAOFReplayMissingFile(t *testing.T) {
+	c := NewMiniCache(0, 0)
+	defer c.Close()
+	if err := c.ReplayAOF(filepath.Join(t.TempDir(), "missing.aof")); err != nil {
+		t.Fatalf("ReplayAOF() = %v, want nil", err)
+	}
+}
+
+func TestAOFTruncatedRecord(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "cache.aof")
+	c := NewMiniCache(0, 0, WithAOF(f, 0, nil))
+	c.Set("a", 1, 0)
+	c.Set("b", 2, 0)
+	c.Close()
+	fi, err := os.Stat(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(f, fi.Size()-1); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewMiniCache(0, 0)
+	defer r.Close()
+	if err := r.ReplayAOF(f); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := r.Get("a"); !found {
+		t.Fatal("complete record a was not replayed")
+	}
+	if _, found := r.Get("b"); found {
+		t.Fatal("truncated record b was replayed")
+	}
+}
+
+func TestAOFRewrite(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "cache.aof")
+	c := NewMiniCache(0, 0, WithAOF(f, 0, nil))
+	for i := 0; i < 100; i++ {
+		c.Set("k", i, 0)
+	}
+	c.Set("other", "x", 0)
+	before, err := os.Stat(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.RewriteAOF(); err != nil {
+		t.Fatal(err)
+	}
+	c.Set("after", true, 0)
+	c.Close()
+	after, err := os.Stat(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Size() >= before.Size() {
+		t.Fatalf("log size after rewrite = %d, want < %d", after.Size(), before.Size())
+	}
+
+	r := NewMiniCache(0, 0)
+	defer r.Close()
+	if err := r.ReplayAOF(f); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := r.Get("k"); v != 99 {
+		t.Fatalf(`Get("k") = %v, want 99`, v)
+	}
+	if n := r.Count(); n != 3 {
+		t.Fatalf("Count() = %d, want 3", n)
+	}
+}
@@ -0,0 +1,128 @@
+package minicache
+
+import "container/list"
+
+//ARC中键所在的队列
+const (
+	arcT1 = iota //只访问过一次的键
+	arcT2        //访问过多次的键
+	arcB1        //从T1淘汰的幽灵键,只保留键不保留数据
+	arcB2        //从T2淘汰的幽灵键
+)
+
+type arcEntry struct {
+	where int
+	elem  *list.Element
+}
+
+//自适应替换缓存,根据幽灵队列的命中在最近性与频率之间调整T1的目标大小p
+type arc struct {
+	c       int
+	p       int
+	lists   [4]*list.List //表头为最近访问
+	entries map[string]*arcEntry
+	fromB2  bool //最近一次写入是否命中B2
+}
+
+func newARC(c int) *arc {
+	p := &arc{c: c, entries: map[string]*arcEntry{}}
+	for i := range p.lists {
+		p.lists[i] = list.New()
+	}
+	return p
+}
+
+func (p *arc) move(k string, e *arcEntry, where int) {
+	p.lists[e.where].Remove(e.elem)
+	e.where = where
+	e.elem = p.lists[where].PushFront(k)
+}
+
+func (p *arc) add(k string) {
+	e, ok := p.entries[k]
+	if !ok {
+		p.fromB2 = false
+		p.entries[k] = &arcEntry{where: arcT1, elem: p.lists[arcT1].PushFront(k)}
+		p.trimGhosts()
+		return
+	}
+	switch e.where {
+	case arcT1, arcT2:
+		p.move(k, e, arcT2)
+		return
+	case arcB1:
+		p.p = min(p.c, p.p+max(p.lists[arcB2].Len()/max(p.lists[arcB1].Len(), 1), 1))
+		p.fromB2 = false
+	case arcB2:
+		p.p = max(0, p.p-max(p.lists[arcB1].Len()/max(p.lists[arcB2].Len(), 1), 1))
+		p.fromB2 = true
+	}
+	p.move(k, e, arcT2)
+}
+
+func (p *arc) access(k string) {
+	if e, ok := p.entries[k]; ok && (e.where == arcT1 || e.where == arcT2) {
+		p.move(k, e, arcT2)
+	}
+}
+
+//数据项被删除或过期时直接移除,不留下幽灵键,再次写入按新键处理
+func (p *arc) remove(k string) {
+	e, ok := p.entries[k]
+	if !ok {
+		return
+	}
+	p.lists[e.where].Remove(e.elem)
+	delete(p.entries, k)
+}
+
+//数据项被淘汰时转入对应的幽灵队列
+func (p *arc) evicted(k string) {
+	e, ok := p.entries[k]
+	if !ok {
+		return
+	}
+	switch e.where {
+	case arcT1:
+		p.move(k, e, arcB1)
+	case arcT2:
+		p.move(k, e, arcB2)
+	}
+	p.trimGhosts()
+}
+
+//幽灵队列与实际队列合计不超过2c,T1与B1合计不超过c
+func (p *arc) trimGhosts() {
+	for p.lists[arcT1].Len()+p.lists[arcB1].Len() > p.c && p.lists[arcB1].Len() > 0 {
+		p.drop(arcB1)
+	}
+	for p.lists[arcT1].Len()+p.lists[arcT2].Len()+p.lists[arcB1].Len()+p.lists[arcB2].Len() > 2*p.c && p.lists[arcB2].Len() > 0 {
+		p.drop(arcB2)
+	}
+}
+
+func (p *arc) drop(where int) {
+	back := p.lists[where].Back()
+	p.lists[where].Remove(back)
+	delete(p.entries, back.Value.(string))
+}
+
+func (p *arc) victim() (string, bool) {
+	t1, t2 := p.lists[arcT1], p.lists[arcT2]
+	if t1.Len() > 0 && (t1.Len() > p.p || (p.fromB2 && t1.Len() == p.p) || t2.Len() == 0) {
+		return t1.Back().Value.(string), true
+	}
+	if t2.Len() > 0 {
+		return t2.Back().Value.(string), true
+	}
+	return "", false
+}
+
+func (p *arc) reset() {
+	for _, l := range p.lists {
+		l.Init()
+	}
+	p.entries = map[string]*arcEntry{}
+	p.p = 0
+	p.fromB2 = false
+}
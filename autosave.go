@@ -0,0 +1,45 @@
+package minicache
+
+import "time"
+
+//每隔interval将缓存保存到fileName,保存使用SaveToFile,不阻塞读写
+//创建缓存时同步执行ValidatePersistence检查持久化配置;检查和保存的错误传给onError(可以为nil),之后的保存照常进行
+//最近一次检查或保存的错误可以通过PersistenceError读取,onError为nil时错误不会被忽略
+//同时相当于WithPersistence(fileName),Shutdown时最后保存一次
+func WithAutoSave(fileName string, interval time.Duration, onError func(error)) Option {
+	return func(minic *Minicache) {
+		minic.persistFile = fileName
+		minic.autoSaveInterval = interval
+		minic.onAutoSaveError = onError
+	}
+}
+
+func (minic *minicache) autoSaveLoop() {
+	defer minic.background.Done()
+	ticker := time.NewTicker(minic.autoSaveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			minic.autoSaved(minic.SaveToFile(minic.persistFile))
+		case <-minic.done:
+			return
+		}
+	}
+}
+
+//记录检查或保存的结果,失败时调用onError
+func (minic *minicache) autoSaved(err error) {
+	minic.autoSaveErr.Store(&err)
+	if err != nil && minic.onAutoSaveError != nil {
+		minic.onAutoSaveError(err)
+	}
+}
+
+//最近一次自动保存的错误,创建缓存后到第一次保存前为持久化检查的错误,保存成功后为nil;未开启WithAutoSave时返回nil
+func (minic *minicache) PersistenceError() error {
+	if err := minic.autoSaveErr.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
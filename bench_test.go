@@ -0,0 +1,100 @@
+package minicache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+const benchKeys = 1024
+
+func benchKeySet() []string {
+	keys := make([]string, benchKeys)
+	for i := range keys {
+		keys[i] = "key" + strconv.Itoa(i)
+	}
+	return keys
+}
+
+func BenchmarkGet(b *testing.B) {
+	c := NewMiniCache(0, 0)
+	defer c.Close()
+	keys := benchKeySet()
+	for _, k := range keys {
+		c.Set(k, k, NoExpiration)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Get(keys[i%benchKeys])
+	}
+}
+
+func BenchmarkGetMiss(b *testing.B) {
+	c := NewMiniCache(0, 0)
+	defer c.Close()
+	keys := benchKeySet()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Get(keys[i%benchKeys])
+	}
+}
+
+func BenchmarkGetParallel(b *testing.B) {
+	c := NewMiniCache(0, 0)
+	defer c.Close()
+	keys := benchKeySet()
+	for _, k := range keys {
+		c.Set(k, k, NoExpiration)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			c.Get(keys[i%benchKeys])
+		}
+	})
+}
+
+func BenchmarkShardedGetParallel(b *testing.B) {
+	sc := NewShardedCache(0, 0)
+	defer sc.Close()
+	keys := benchKeySet()
+	for _, k := range keys {
+		sc.Set(k, k, NoExpiration)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			sc.Get(keys[i%benchKeys])
+		}
+	})
+}
+
+func BenchmarkSet(b *testing.B) {
+	c := NewMiniCache(0, 0)
+	defer c.Close()
+	keys := benchKeySet()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Set(keys[i%benchKeys], i, NoExpiration)
+	}
+}
+
+//持续写入短有效期的键并清理过期数据项,过期堆节点来自对象池
+func BenchmarkSetExpireChurn(b *testing.B) {
+	c := NewMiniCache(0, 0)
+	defer c.Close()
+	keys := benchKeySet()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Set(keys[i%benchKeys], i, time.Nanosecond)
+		if i%benchKeys == benchKeys-1 {
+			c.DeleteExpired()
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package minicache
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+//Minicache和ShardedCache共同的方法,调用方依赖Cache时可以在两者之间切换
+//调整容量的Resize两者的参数不同,不包含在内
+type Cache interface {
+	//写入
+	Set(k string, v interface{}, d time.Duration) error
+	SetForever(k string, v interface{}) error
+	SetWithExpireAt(k string, v interface{}, t time.Time) error
+	SetSliding(k string, v interface{}, d time.Duration) error
+	SetWithCost(k string, v interface{}, cost int64, d time.Duration) error
+	SetWithPriority(k string, v interface{}, d time.Duration, priority int) error
+	SetScoped(ctx context.Context, k string, v interface{}) error
+	Add(k string, v interface{}, d time.Duration) error
+	AddOrGet(k string, v interface{}, d time.Duration) (interface{}, error)
+	Replace(k string, v interface{}, d time.Duration) error
+	Upsert(k string, v interface{}, d time.Duration, merge func(existing, new interface{}) interface{}) error
+	WithLock(k string, fn func(v interface{}, found bool) (interface{}, bool)) error
+	Claim(k, ownerID string, d time.Duration) (currentOwner string, acquired bool)
+	SetIfGreater(k string, v int64, d time.Duration) (updated bool)
+	SetIfLess(k string, v int64, d time.Duration) (updated bool)
+	Delete(k string)
+	Flush()
+	Wait()
+
+	//读取
+	Get(k string) (interface{}, bool)
+	GetMultiTouch(keys []string, extend time.Duration) map[string]interface{}
+	GetStale(k string) (v interface{}, expired bool, found bool)
+	GetWithMeta(k string) (ItemMeta, bool)
+	GetOrLoad(k string) (interface{}, error)
+	Memoize(k string, d time.Duration, fn func() (interface{}, error)) (interface{}, error)
+	RegisterLoader(prefix string, loader Loader)
+	Warm(ctx context.Context, keys []string, loader WarmLoader, concurrency int) error
+	Inspect(k string) (Item, bool)
+	Range(fn func(k string, v interface{}) bool)
+	Count() int
+
+	//有效期
+	TTL(k string) (time.Duration, bool)
+	Touch(k string) bool
+	Expire(k string, d time.Duration) bool
+	ExpireAt(k string, t time.Time) bool
+	Persist(k string) bool
+	Revalidate(k string, d time.Duration) bool
+	ExpiringWithin(d time.Duration) []string
+	SetDefaultExpiration(d time.Duration)
+	SetPrefixExpiration(prefix string, d time.Duration)
+	ClearPrefixExpiration(prefix string)
+	OverrideTTL(keyOrPrefix string, d time.Duration, until time.Time)
+	ClearTTLOverride(keyOrPrefix string)
+	DeleteExpired() int
+	DeleteExpiredFunc(fn func(k string, v interface{})) int
+	Stopgc()
+
+	//淘汰
+	Pin(k string)
+	Unpin(k string)
+
+	//回调和事件
+	OnExpired(fn func(k string, v interface{}))
+	OnEvicted(fn func(k string, v interface{}, reason EvictionReason))
+	OnGC(fn func(GCStats))
+	OnClose(fn func() error)
+	Subscribe(filter EventFilter, buffer int, policy BufferPolicy) *Subscription
+	ExpiredChan(buffer int) <-chan ExpiredEvent
+	Trace(k string, w io.Writer, d time.Duration)
+
+	//统计
+	Stats() Stats
+	GCStats() GCStats
+	Latency(op LatencyOp) LatencySnapshot
+	NamespaceStats() map[string]NamespaceStats
+	KeyCardinality() uint64
+	DeadEntries() map[string]uint64
+	TopKeys(n int) []HotKey
+	SimulationReport() []SimulationStats
+
+	//持久化和生命周期
+	Save(w io.Writer) error
+	SaveToFile(fileName string) error
+	Load(r io.Reader) error
+	LoadFromFile(fileName string) error
+	ValidatePersistence(fileName string) error
+	ReplayAOF(fileName string) error
+	RewriteAOF() error
+	Freeze()
+	Frozen() bool
+	Close() error
+	Shutdown(ctx context.Context) error
+}
+
+var (
+	_ Cache = (*Minicache)(nil)
+	_ Cache = (*ShardedCache)(nil)
+)
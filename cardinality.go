@@ -0,0 +1,123 @@
+package minicache
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"sync"
+	"time"
+)
+
+//HyperLogLog精度,4096个寄存器,标准误差约1.6%
+const (
+	hllPrecision = 12
+	hllRegisters = 1 << hllPrecision
+)
+
+//HyperLogLog基数估计
+type hll struct {
+	minute int64
+	reg    [hllRegisters]uint8
+}
+
+func (h *hll) add(x uint64) {
+	idx := x >> (64 - hllPrecision)
+	w := x<<hllPrecision | 1<<(hllPrecision-1)
+	if rho := uint8(bits.LeadingZeros64(w)) + 1; rho > h.reg[idx] {
+		h.reg[idx] = rho
+	}
+}
+
+func (h *hll) merge(o *hll) {
+	for i, r := range o.reg {
+		if r > h.reg[i] {
+			h.reg[i] = r
+		}
+	}
+}
+
+func (h *hll) estimate() uint64 {
+	m := float64(hllRegisters)
+	var sum float64
+	zeros := 0
+	for _, r := range h.reg {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	e := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if e <= 2.5*m && zeros > 0 {
+		e = m * math.Log(m/float64(zeros))
+	}
+	return uint64(e + 0.5)
+}
+
+//滑动窗口内的键基数统计,每分钟一个sketch
+type cardinality struct {
+	mtx     sync.Mutex
+	windows []hll
+}
+
+func hashKey(k string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(k))
+	//fnv低位分布较差,再做一次混合
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+func (c *cardinality) add(k string) {
+	x := hashKey(k)
+	minute := time.Now().Unix() / 60
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	w := &c.windows[minute%int64(len(c.windows))]
+	if w.minute != minute {
+		*w = hll{minute: minute}
+	}
+	w.add(x)
+}
+
+func (c *cardinality) estimate() uint64 {
+	var merged hll
+	c.mergeInto(&merged)
+	return merged.estimate()
+}
+
+//把窗口内的sketch合并到h
+func (c *cardinality) mergeInto(h *hll) {
+	minute := time.Now().Unix() / 60
+	oldest := minute - int64(len(c.windows)) + 1
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for i := range c.windows {
+		if c.windows[i].minute >= oldest {
+			h.merge(&c.windows[i])
+		}
+	}
+}
+
+//统计最近window时长内被访问过的不同键的数量(按分钟取整),基于HyperLogLog估计
+func WithKeyCardinality(window time.Duration) Option {
+	return func(minic *Minicache) {
+		n := int((window + time.Minute - 1) / time.Minute)
+		if n < 1 {
+			n = 1
+		}
+		minic.cardinality = &cardinality{windows: make([]hll, n)}
+	}
+}
+
+//返回最近窗口内被访问过的不同键的估计数量,未开启时返回0
+func (minic *minicache) KeyCardinality() uint64 {
+	if minic.cardinality == nil {
+		return 0
+	}
+	return minic.cardinality.estimate()
+}
@@ -0,0 +1,76 @@
+package minicache
+
+import "sync/atomic"
+
+type clockEntry struct {
+	key string
+	ref uint32 //访问位,命中时原子置1
+}
+
+//时钟置换:键排成环,指针扫过时访问位为1的键清零并获得第二次机会,为0的键被淘汰
+//命中只原子设置访问位,不移动任何节点,因此只需evictor的读锁
+type clock struct {
+	ring  []clockEntry
+	index map[string]int
+	hand  int
+}
+
+func newClock() *clock {
+	return &clock{index: map[string]int{}}
+}
+
+func (p *clock) add(k string) {
+	if i, ok := p.index[k]; ok {
+		p.ring[i].ref = 1
+		return
+	}
+	p.index[k] = len(p.ring)
+	p.ring = append(p.ring, clockEntry{key: k})
+}
+
+func (p *clock) access(k string) {
+	p.accessShared(k)
+}
+
+func (p *clock) accessShared(k string) {
+	if i, ok := p.index[k]; ok {
+		atomic.StoreUint32(&p.ring[i].ref, 1)
+	}
+}
+
+//用环上最后一个键填补被删除的位置
+func (p *clock) remove(k string) {
+	i, ok := p.index[k]
+	if !ok {
+		return
+	}
+	last := len(p.ring) - 1
+	if i != last {
+		p.ring[i] = p.ring[last]
+		p.index[p.ring[i].key] = i
+	}
+	p.ring = p.ring[:last]
+	delete(p.index, k)
+	if p.hand >= len(p.ring) {
+		p.hand = 0
+	}
+}
+
+//最多转两圈:第一圈清零所有访问位后,第二圈必然找到访问位为0的键
+func (p *clock) victim() (string, bool) {
+	for n := 0; n < 2*len(p.ring); n++ {
+		e := &p.ring[p.hand]
+		if e.ref == 0 {
+			return e.key, true
+		}
+		e.ref = 0
+		p.hand = (p.hand + 1) % len(p.ring)
+	}
+	return "", false
+}
+
+func (p *clock) reset() {
+	p.ring = nil
+	p.index = map[string]int{}
+	p.hand = 0
+}
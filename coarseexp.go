@@ -0,0 +1,152 @@
+package minicache
+
+import (
+	"math"
+	"time"
+)
+
+//按秒保存过期时间,数据项在内部以紧凑格式存储,每个数据项少占16字节,过期时间向上取整到整秒
+//用于保存大量小数据项、不需要亚秒级过期精度的缓存;Item.Expiration仍以纳秒表示,Inspect等返回取整后的值
+//过期时间保存为相对创建缓存时刻的uint32秒数,最远约136年,超出的部分按最远时间保存;优先级保存为int32
+func WithCoarseExpiration() Option {
+	return func(minic *Minicache) {
+		minic.items.coarse = true
+	}
+}
+
+//数据项的存储,开启WithCoarseExpiration时使用compact,否则使用full
+type itemStore struct {
+	full    map[string]Item
+	compact map[string]compactItem
+	coarse  bool
+	epoch   int64 //compact中过期时间的起点,Unix秒
+}
+
+//紧凑格式的数据项,字段含义同Item
+type compactItem struct {
+	Object   interface{}
+	sliding  time.Duration
+	meta     *itemMeta
+	shared   *dedupEntry
+	cost     int64
+	priority int32
+	exp      uint32 //相对epoch的秒数,0表示永不过期
+	source   ItemSource
+	read     bool
+}
+
+//按n个数据项预分配,清空已有的数据项
+func (s *itemStore) reset(n int) {
+	if !s.coarse {
+		s.full = make(map[string]Item, n)
+		return
+	}
+	if s.epoch == 0 {
+		//往前留一秒,早于创建时刻的过期时间保存为1后仍然是已过期
+		s.epoch = time.Now().Unix() - 1
+	}
+	s.compact = make(map[string]compactItem, n)
+}
+
+func (s *itemStore) get(k string) (Item, bool) {
+	if !s.coarse {
+		item, found := s.full[k]
+		return item, found
+	}
+	c, found := s.compact[k]
+	if !found {
+		return Item{}, false
+	}
+	return s.expand(c), true
+}
+
+func (s *itemStore) set(k string, item Item) {
+	if !s.coarse {
+		s.full[k] = item
+		return
+	}
+	s.compact[k] = compactItem{
+		Object:   item.Object,
+		sliding:  item.Sliding,
+		meta:     item.meta,
+		shared:   item.shared,
+		cost:     item.cost,
+		priority: int32(max(min(item.priority, math.MaxInt32), math.MinInt32)),
+		exp:      s.seconds(item.Expiration),
+		source:   item.Source,
+		read:     item.read,
+	}
+}
+
+func (s *itemStore) remove(k string) {
+	if !s.coarse {
+		delete(s.full, k)
+		return
+	}
+	delete(s.compact, k)
+}
+
+func (s *itemStore) len() int {
+	if !s.coarse {
+		return len(s.full)
+	}
+	return len(s.compact)
+}
+
+//遍历数据项,fn返回false时停止;fn中可以删除数据项
+func (s *itemStore) each(fn func(k string, item Item) bool) {
+	if !s.coarse {
+		for k, item := range s.full {
+			if !fn(k, item) {
+				return
+			}
+		}
+		return
+	}
+	for k, c := range s.compact {
+		if !fn(k, s.expand(c)) {
+			return
+		}
+	}
+}
+
+//过期时间e按存储精度取整后的值,写入后读出的过期时间与之相同;用于登记过期堆,保证与存储的过期时间一致
+func (s *itemStore) round(e int64) int64 {
+	if !s.coarse || e == 0 {
+		return e
+	}
+	return s.nanos(s.seconds(e))
+}
+
+//纳秒时间点转换为相对epoch的秒数,向上取整,不早于epoch+1
+func (s *itemStore) seconds(e int64) uint32 {
+	if e == 0 {
+		return 0
+	}
+	sec := e / int64(time.Second)
+	if e%int64(time.Second) > 0 {
+		sec++
+	}
+	return uint32(max(min(sec-s.epoch, math.MaxUint32), 1))
+}
+
+func (s *itemStore) nanos(exp uint32) int64 {
+	if exp == 0 {
+		return 0
+	}
+	return (s.epoch + int64(exp)) * int64(time.Second)
+}
+
+func (s *itemStore) expand(c compactItem) Item {
+	return Item{
+		Object:     c.Object,
+		Expiration: s.nanos(c.exp),
+		Source:     c.source,
+		Sliding:    c.sliding,
+		read:       c.read,
+		meta:       c.meta,
+		shared:     c.shared,
+		cost:       c.cost,
+		priority:   int(c.priority),
+	}
+}
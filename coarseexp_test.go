@@ -0,0 +1,62 @@
+package minicache
+
+import (
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func TestCompactItemSize(t *testing.T) {
+	if full, compact := unsafe.Sizeof(Item{}), unsafe.Sizeof(compactItem{}); compact+16 > full {
+		t.Fatalf("compactItem is %d bytes, Item is %d", compact, full)
+	}
+}
+
+func TestCoarseExpiration(t *testing.T) {
+	c := NewMiniCache(0, 0, WithCoarseExpiration())
+	defer c.Close()
+	c.Set("forever", 1, NoExpiration)
+	c.Set("short", 2, 1500*time.Millisecond)
+	at := time.Now().Add(time.Hour)
+	c.SetWithExpireAt("at", 3, at)
+
+	if item, _ := c.Inspect("forever"); item.Expiration != 0 {
+		t.Fatalf("forever Expiration = %d, want 0", item.Expiration)
+	}
+	item, _ := c.Inspect("at")
+	if item.Expiration%int64(time.Second) != 0 || item.Expiration < at.UnixNano() || item.Expiration-at.UnixNano() >= int64(time.Second) {
+		t.Fatalf("Expiration = %v, want %v rounded up to a second", time.Unix(0, item.Expiration), at)
+	}
+	if v, found := c.Get("short"); !found || v != 2 {
+		t.Fatalf("Get(short) = %v, %v", v, found)
+	}
+	//向上取整到整秒,不会提前过期
+	if ttl, _ := c.TTL("short"); ttl < 1400*time.Millisecond || ttl > 2600*time.Millisecond {
+		t.Fatalf("TTL(short) = %v", ttl)
+	}
+	if !c.Expire("short", time.Nanosecond) {
+		t.Fatal("Expire(short) = false")
+	}
+	time.Sleep(1100 * time.Millisecond)
+	if _, found := c.Get("short"); found {
+		t.Fatal("short did not expire")
+	}
+	//过期堆与存储的过期时间一致,gc能删除到期的数据项
+	c.Set("gc", 4, time.Nanosecond)
+	time.Sleep(1100 * time.Millisecond)
+	if n := c.DeleteExpired(); n != 1 {
+		t.Fatalf("DeleteExpired() = %d, want 1", n)
+	}
+	if n := c.Count(); n != 2 {
+		t.Fatalf("Count() = %d, want 2", n)
+	}
+}
+
+func TestCoarseExpirationPastDeadline(t *testing.T) {
+	c := NewMiniCache(0, 0, WithCoarseExpiration())
+	defer c.Close()
+	c.restore("old", Item{Object: 1, Expiration: time.Now().Add(-time.Hour).UnixNano()})
+	if _, found := c.Get("old"); found {
+		t.Fatal("item that expired before the cache was created is visible")
+	}
+}
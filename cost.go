@@ -0,0 +1,47 @@
+package minicache
+
+import "time"
+
+//限制所有数据项的开销之和,超过total时按淘汰策略淘汰
+//开销由SetWithCost指定,其他写入方式每个数据项的开销为1,开启WithMaxMemory时为估计的内存占用
+func WithMaxCost(total int64) Option {
+	return func(minic *Minicache) {
+		minic.maxCost = total
+	}
+}
+
+//以指定开销写入数据项,开销通常为序列化后的大小,cost不大于0时按默认方式计算
+//开销超过WithMaxCost上限的数据项不会被保留
+func (minic *minicache) SetWithCost(k string, v interface{}, cost int64, d time.Duration) error {
+	if minic.latency != nil {
+		defer minic.latency.since(LatencySet, time.Now())
+	}
+	if closed, err := minic.closed(); closed {
+		return err
+	}
+	//指定了开销时以开销作为值的大小
+	if cost > 0 {
+		if minic.maxItemSize > 0 && cost > minic.maxItemSize {
+			_, err := minic.oversized(k)
+			return err
+		}
+	} else if over, err := minic.oversize(k, v); over {
+		return err
+	}
+	minic.rwmtx.Lock()
+	defer minic.writeUnlock()
+	item := minic.newItem(k, v, d, SourceSet, minic.sliding)
+	item.cost = cost
+	return minic.store(k, item)
+}
+
+//数据项的默认开销,配置了Sizer时为估计的内存占用,否则为1
+func (minic *minicache) costOf(k string, v interface{}) int64 {
+	if minic.sizer == nil {
+		return 1
+	}
+	if minic.items.coarse {
+		return int64(len(k)) + compactOverhead + minic.sizer.Size(v)
+	}
+	return int64(len(k)) + itemOverhead + minic.sizer.Size(v)
+}
@@ -0,0 +1,72 @@
+package minicache
+
+import (
+	"strings"
+	"sync"
+)
+
+//单独统计的键前缀数量上限,超出后计入deadOverflow
+const maxDeadPrefixes = 1024
+
+//超出前缀数量上限时使用的统计项
+const deadOverflow = "<other>"
+
+//写入后直到过期或被淘汰都未被读取的数据项统计
+type deadEntries struct {
+	sep    string
+	mtx    sync.Mutex
+	counts map[string]uint64
+}
+
+//键前缀,取到第一个分隔符(包含)为止,没有分隔符时为空字符串
+func (d *deadEntries) prefix(k string) string {
+	if i := strings.Index(k, d.sep); i >= 0 {
+		return k[:i+len(d.sep)]
+	}
+	return ""
+}
+
+func (d *deadEntries) record(k string) {
+	p := d.prefix(k)
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if _, ok := d.counts[p]; !ok && len(d.counts) >= maxDeadPrefixes {
+		p = deadOverflow
+	}
+	d.counts[p]++
+}
+
+//统计写入后从未被读取就过期或被容量淘汰的数据项,按键中第一个sep之前的前缀汇总
+func WithDeadEntryReport(sep string) Option {
+	return func(minic *Minicache) {
+		minic.deadEntries = &deadEntries{sep: sep, counts: map[string]uint64{}}
+	}
+}
+
+//返回按前缀汇总的未读即过期或被淘汰的数据项数量,未开启时返回nil
+func (minic *minicache) DeadEntries() map[string]uint64 {
+	d := minic.deadEntries
+	if d == nil {
+		return nil
+	}
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	report := make(map[string]uint64, len(d.counts))
+	for p, n := range d.counts {
+		report[p] = n
+	}
+	return report
+}
+
+//标记数据项已被读取
+func (minic *minicache) markRead(k string) {
+	if minic.Frozen() {
+		return
+	}
+	minic.rwmtx.Lock()
+	defer minic.rwmtx.Unlock()
+	if item, found := minic.items.get(k); found && !item.read {
+		item.read = true
+		minic.storeItem(k, item)
+	}
+}
@@ -0,0 +1,39 @@
+package minicache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeadEntriesExpired(t *testing.T) {
+	c := NewMiniCache(0, 0, WithDeadEntryReport(":"))
+	defer c.Close()
+	c.Set("user:1", 1, time.Millisecond)
+	c.Set("user:2", 2, time.Millisecond)
+	c.Get("user:2")
+	time.Sleep(5 * time.Millisecond)
+	c.DeleteExpired()
+	if got := c.DeadEntries()["user:"]; got != 1 {
+		t.Fatalf(`DeadEntries()["user:"] = %d, want 1`, got)
+	}
+}
+
+func TestDeadEntriesEvicted(t *testing.T) {
+	c := NewMiniCache(0, 0, WithMaxEntries(2), WithDeadEntryReport(":"))
+	defer c.Close()
+	c.Set("user:1", 1, 0)
+	c.Set("user:2", 2, 0)
+	c.Get("user:2")
+	c.Set("user:3", 3, 0)
+	c.Get("user:3")
+	if _, found := c.Get("user:1"); found {
+		t.Fatal("user:1 should have been evicted")
+	}
+	if got := c.DeadEntries()["user:"]; got != 1 {
+		t.Fatalf(`DeadEntries()["user:"] = %d, want 1`, got)
+	}
+	c.Delete("user:2")
+	if got := c.DeadEntries()["user:"]; got != 1 {
+		t.Fatalf(`after Delete DeadEntries()["user:"] = %d, want 1`, got)
+	}
+}
@@ -0,0 +1,116 @@
+package minicache
+
+import (
+	"bytes"
+	"hash/maphash"
+)
+
+//共享的值及其引用计数
+type dedupEntry struct {
+	value interface{}
+	hash  uint64
+	refs  int
+}
+
+//按内容去重的值表,只处理长度不小于minSize的[]byte和string
+//缓存写锁保护,不单独加锁
+type dedupTable struct {
+	minSize int
+	seed    maphash.Seed
+	entries map[uint64][]*dedupEntry
+}
+
+//相同内容的[]byte或string值(长度不小于minSize)在多个键之间共享同一份数据
+//写入的[]byte第一次登记时复制一份作为共享数据,之后修改传入的切片不影响缓存
+//共享的[]byte按写时复制使用:Get返回的切片不能修改,需要修改时应复制后重新Set
+func WithValueDedup(minSize int) Option {
+	return func(minic *Minicache) {
+		minic.dedup = &dedupTable{
+			minSize: minSize,
+			seed:    maphash.MakeSeed(),
+			entries: map[uint64][]*dedupEntry{},
+		}
+	}
+}
+
+//返回值的内容,不参与去重时ok为false
+func (t *dedupTable) content(v interface{}) (b []byte, s string, ok bool) {
+	switch x := v.(type) {
+	case []byte:
+		return x, "", len(x) >= t.minSize
+	case string:
+		return nil, x, len(x) >= t.minSize
+	}
+	return nil, "", false
+}
+
+func (t *dedupTable) hash(b []byte, s string) uint64 {
+	if b != nil {
+		return maphash.Bytes(t.seed, b)
+	}
+	return maphash.String(t.seed, s)
+}
+
+//查找内容相同的共享值
+func (t *dedupTable) find(h uint64, v interface{}, b []byte, s string) *dedupEntry {
+	for _, e := range t.entries[h] {
+		switch x := e.value.(type) {
+		case []byte:
+			if _, isBytes := v.([]byte); isBytes && bytes.Equal(x, b) {
+				return e
+			}
+		case string:
+			if _, isString := v.(string); isString && x == s {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+//返回与v内容相同的共享值并增加引用,没有时登记v,[]byte登记的是v的副本
+//返回的登记项用于release,v不参与去重时为nil
+func (t *dedupTable) intern(v interface{}) (interface{}, *dedupEntry) {
+	b, s, ok := t.content(v)
+	if !ok {
+		return v, nil
+	}
+	h := t.hash(b, s)
+	if e := t.find(h, v, b, s); e != nil {
+		e.refs++
+		return e.value, e
+	}
+	if b != nil {
+		v = bytes.Clone(b)
+	}
+	e := &dedupEntry{value: v, hash: h, refs: 1}
+	t.entries[h] = append(t.entries[h], e)
+	return v, e
+}
+
+//释放共享值的一次引用,引用归零时从表中移除
+//按登记时的哈希查找,共享的切片被修改过时同样能移除,e为nil时直接返回
+func (t *dedupTable) release(e *dedupEntry) {
+	if e == nil {
+		return
+	}
+	if e.refs--; e.refs > 0 {
+		return
+	}
+	list := t.entries[e.hash]
+	for i := range list {
+		if list[i] == e {
+			list = append(list[:i], list[i+1:]...)
+			break
+		}
+	}
+	if len(list) == 0 {
+		delete(t.entries, e.hash)
+	} else {
+		t.entries[e.hash] = list
+	}
+}
+
+func (t *dedupTable) reset() {
+	t.entries = map[uint64][]*dedupEntry{}
+}
@@ -0,0 +1,50 @@
+package minicache
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDedupCopiesInput(t *testing.T) {
+	c := NewMiniCache(0, 0, WithValueDedup(4))
+	defer c.Close()
+	buf := []byte("rendered fragment")
+	c.Set("a", buf, 0)
+	shared, _ := c.Get("a")
+	if &shared.([]byte)[0] == &buf[0] {
+		t.Fatal("the caller's slice became the shared buffer")
+	}
+	copy(buf, "REUSED BUFFER....")
+	if v, _ := c.Get("a"); !bytes.Equal(v.([]byte), []byte("rendered fragment")) {
+		t.Fatalf(`Get("a") = %q after the caller reused its buffer`, v)
+	}
+	c.Set("b", []byte("rendered fragment"), 0)
+	b, _ := c.Get("b")
+	if &b.([]byte)[0] != &shared.([]byte)[0] {
+		t.Fatal("equal values were not shared")
+	}
+	c.Set("c", buf, 0)
+	if v, _ := c.Get("c"); !bytes.Equal(v.([]byte), buf) {
+		t.Fatalf(`Get("c") = %q, want %q`, v, buf)
+	}
+}
+
+func TestDedupReleases(t *testing.T) {
+	c := NewMiniCache(0, 0, WithValueDedup(4))
+	defer c.Close()
+	for _, k := range []string{"a", "b", "c"} {
+		c.Set(k, []byte("shared body"), 0)
+	}
+	c.Set("s", "shared string", 0)
+	//修改共享的切片后仍能按登记项释放
+	v, _ := c.Get("a")
+	copy(v.([]byte), "mutated....")
+	c.Set("a", "replacement", 0)
+	c.Delete("b")
+	c.Delete("c")
+	c.Delete("s")
+	c.Delete("a")
+	if n := len(c.dedup.entries); n != 0 {
+		t.Fatalf("dedup table holds %d hashes after all keys were removed", n)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package minicache
+
+//当前平台不支持查询可用空间
+func diskFree(dir string) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,70 @@
+package minicache
+
+import "time"
+
+//每个键占用的过滤器位数与哈希函数个数,误判率约1%
+const (
+	doorkeeperBitsPerKey = 10
+	doorkeeperHashes     = 7
+)
+
+//门卫布隆过滤器,记录一个窗口内写入过一次的新键,窗口结束时清空
+type doorkeeper struct {
+	bits    []uint64
+	mask    uint64
+	window  time.Duration
+	resetAt time.Time
+}
+
+func newDoorkeeper(capacity int, window time.Duration) *doorkeeper {
+	n := uint64(64)
+	for n < uint64(capacity)*doorkeeperBitsPerKey {
+		n <<= 1
+	}
+	return &doorkeeper{bits: make([]uint64, n/64), mask: n - 1, window: window, resetAt: time.Now().Add(window)}
+}
+
+//已记录过k时返回true,否则记录k并返回false
+func (d *doorkeeper) seen(k string) bool {
+	if now := time.Now(); d.window > 0 && !now.Before(d.resetAt) {
+		clear(d.bits)
+		d.resetAt = now.Add(d.window)
+	}
+	h := hashKey(k)
+	step := h>>32 | 1
+	found := true
+	for i := uint64(0); i < doorkeeperHashes; i++ {
+		bit := (h + i*step) & d.mask
+		word, mask := &d.bits[bit/64], uint64(1)<<(bit%64)
+		if *word&mask == 0 {
+			found = false
+			*word |= mask
+		}
+	}
+	return found
+}
+
+//有容量上限的缓存中,新键在window时长内第二次写入时才真正写入,第一次写入只被记录
+//用于过滤爬虫式的一次性访问,window不大于0时不清空过滤器;被固定的键不受限制
+//只过滤显式写入,被过滤的写入返回ErrNotAdmitted;Load、ReplayAOF、Warm和加载函数的写入直接写入
+func WithDoorkeeper(window time.Duration) Option {
+	return func(minic *Minicache) {
+		minic.doorkeeperWindow = window
+		minic.doorkeeperOn = true
+	}
+}
+
+//新键第一次写入时返回false,无锁
+func (minic *minicache) passDoorkeeper(k string) bool {
+	if minic.doorkeeper == nil || minic.pinned(k) {
+		return true
+	}
+	if _, found := minic.items.get(k); found {
+		return true
+	}
+	if minic.doorkeeper.seen(k) {
+		return true
+	}
+	minic.doorkeeperRejects++
+	return false
+}
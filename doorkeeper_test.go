@@ -0,0 +1,86 @@
+package minicache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newDoorkeeperCache() *Minicache {
+	return NewMiniCache(0, 0, WithMaxEntries(1000), WithDoorkeeper(0))
+}
+
+func TestDoorkeeperSet(t *testing.T) {
+	c := newDoorkeeperCache()
+	defer c.Close()
+	if err := c.Set("a", 1, 0); !errors.Is(err, ErrNotAdmitted) {
+		t.Fatalf("first Set error = %v, want %v", err, ErrNotAdmitted)
+	}
+	if _, found := c.Get("a"); found {
+		t.Fatal("first write was cached")
+	}
+	if err := c.Set("a", 1, 0); err != nil {
+		t.Fatalf("second Set error = %v", err)
+	}
+	if err := c.Set("a", 2, 0); err != nil {
+		t.Fatalf("overwrite error = %v", err)
+	}
+	if v, _ := c.Get("a"); v != 2 {
+		t.Fatalf("Get = %v, want 2", v)
+	}
+}
+
+func TestDoorkeeperBypass(t *testing.T) {
+	src := NewMiniCache(0, 0)
+	defer src.Close()
+	for i := 0; i < 100; i++ {
+		src.Set(fmt.Sprint("k", i), i, 0)
+	}
+	var buf bytes.Buffer
+	if err := src.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newDoorkeeperCache()
+	defer c.Close()
+	if err := c.Load(&buf); err != nil || c.Count() != 100 {
+		t.Fatalf("Load: err = %v, Count() = %d, want 100", err, c.Count())
+	}
+
+	loader := func(ctx context.Context, k string) (interface{}, time.Duration, error) {
+		return k, 0, nil
+	}
+	if err := c.Warm(context.Background(), []string{"w1", "w2"}, loader, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := c.Get("w1"); !found {
+		t.Fatal("warmed key was not cached")
+	}
+
+	if _, err := c.Memoize("m", 0, func() (interface{}, error) { return 1, nil }); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := c.Get("m"); !found {
+		t.Fatal("memoized key was not cached")
+	}
+}
+
+func TestDoorkeeperReplayAOF(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "cache.aof")
+	src := NewMiniCache(0, 0, WithAOF(f, 0, nil))
+	for i := 0; i < 10; i++ {
+		src.Set(fmt.Sprint("k", i), i, 0)
+	}
+	if err := src.Close(); err != nil {
+		t.Fatal(err)
+	}
+	c := newDoorkeeperCache()
+	defer c.Close()
+	if err := c.ReplayAOF(f); err != nil || c.Count() != 10 {
+		t.Fatalf("ReplayAOF: err = %v, Count() = %d, want 10", err, c.Count())
+	}
+}
@@ -0,0 +1,30 @@
+package minicache
+
+import "errors"
+
+var (
+	//数据项已存在
+	ErrKeyExists = errors.New("item already exists")
+	//没有匹配键的加载函数
+	ErrNoLoader = errors.New("no loader registered for key")
+	//缓存已关闭
+	ErrCacheClosed = errors.New("cache is closed")
+	//值超过大小上限
+	ErrValueTooLarge = errors.New("value too large")
+	//缓存已满,拒绝写入
+	ErrCacheFull = errors.New("cache is full")
+	//新键第一次写入被门卫过滤
+	ErrNotAdmitted = errors.New("write not admitted by doorkeeper")
+	//缓存已冻结
+	ErrReadOnly = errors.New("cache is read-only")
+	//上一次改变分片数的迁移还没有完成
+	ErrResizing = errors.New("shard resize in progress")
+	//不是快照文件
+	ErrNotASnapshot = errors.New("not a minicache snapshot")
+	//快照格式版本不支持
+	ErrUnsupportedVersion = errors.New("unsupported snapshot version")
+	//快照校验和不一致
+	ErrCorruptSnapshot = errors.New("corrupt snapshot")
+	//快照解密或认证失败
+	ErrDecryptSnapshot = errors.New("snapshot decryption failed")
+)
@@ -0,0 +1,241 @@
+package minicache
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//事件类型,可按位组合用于过滤
+type EventOp uint32
+
+const (
+	EventSet EventOp = 1 << iota
+	EventDelete
+	EventExpire
+	EventFlush
+	EventHit        //Get命中
+	EventMiss       //Get未命中
+	EventExpiration //有效期变更
+	EventExpiring   //即将过期
+	EventEvict      //超出容量被淘汰
+)
+
+var eventOpNames = map[EventOp]string{
+	EventSet:        "set",
+	EventDelete:     "delete",
+	EventExpire:     "expire",
+	EventFlush:      "flush",
+	EventHit:        "hit",
+	EventMiss:       "miss",
+	EventExpiration: "expiration",
+	EventExpiring:   "expiring",
+	EventEvict:      "evict",
+}
+
+func (op EventOp) String() string {
+	if name, ok := eventOpNames[op]; ok {
+		return name
+	}
+	return fmt.Sprintf("op(%d)", uint32(op))
+}
+
+//缓存事件
+type Event struct {
+	Op         EventOp
+	Key        string
+	Object     interface{}
+	Expiration int64
+	Source     ItemSource
+	Time       time.Time
+	Namespace  string   //开启WithNamespaceMetrics时键所属的命名空间,与NamespaceStats的划分相同
+	Tags       []string //WithEventTags设置的函数为数据项返回的标签
+}
+
+//事件过滤条件,零值表示不过滤,各条件同时满足才匹配
+type EventFilter struct {
+	Ops       EventOp
+	Prefix    string
+	Key       string //非空时只匹配该键
+	Namespace string //非空时只匹配该命名空间的键,需要开启WithNamespaceMetrics
+	Tag       string //非空时只匹配带有该标签的事件
+}
+
+func (f EventFilter) match(e Event) bool {
+	if f.Ops != 0 && f.Ops&e.Op == 0 {
+		return false
+	}
+	if f.Namespace != "" && e.Namespace != f.Namespace {
+		return false
+	}
+	if f.Tag != "" && !slices.Contains(e.Tags, f.Tag) {
+		return false
+	}
+	if f.Key != "" {
+		return e.Key == f.Key
+	}
+	return strings.HasPrefix(e.Key, f.Prefix)
+}
+
+//为事件计算标签,用于按EventFilter.Tag订阅;fn在发布事件时调用,可能持有缓存的写锁,应尽快返回
+//Get未命中、清空等没有值的事件以nil调用fn
+func WithEventTags(fn func(k string, v interface{}) []string) Option {
+	return func(minic *Minicache) {
+		minic.eventTags = fn
+	}
+}
+
+//订阅者缓冲区写满时的处理策略
+type BufferPolicy int
+
+const (
+	DropNewest BufferPolicy = iota //丢弃新事件
+	DropOldest                     //丢弃缓冲区中最旧的事件
+)
+
+//事件订阅
+type Subscription struct {
+	hub     *eventHub
+	filter  EventFilter
+	policy  BufferPolicy
+	ch      chan Event
+	dropped uint64
+}
+
+//事件通道,取消订阅后关闭
+func (sub *Subscription) Events() <-chan Event {
+	return sub.ch
+}
+
+//因缓冲区已满丢弃的事件数
+func (sub *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&sub.dropped)
+}
+
+//取消订阅,可重复调用
+func (sub *Subscription) Unsubscribe() {
+	sub.hub.remove(sub)
+}
+
+//投递事件,不阻塞发布方
+func (sub *Subscription) deliver(e Event) {
+	select {
+	case sub.ch <- e:
+		return
+	default:
+	}
+	if sub.policy == DropOldest {
+		select {
+		case <-sub.ch:
+			atomic.AddUint64(&sub.dropped, 1)
+		default:
+		}
+		select {
+		case sub.ch <- e:
+			return
+		default:
+		}
+	}
+	atomic.AddUint64(&sub.dropped, 1)
+}
+
+//事件分发
+type eventHub struct {
+	mtx  sync.RWMutex
+	subs map[*Subscription]struct{}
+	ops  uint32 //所有订阅者关注的事件类型并集
+}
+
+//重新计算订阅的事件类型并集,需持有hub.mtx
+func (hub *eventHub) updateOps() {
+	var ops EventOp
+	for sub := range hub.subs {
+		if sub.filter.Ops == 0 {
+			ops = ^EventOp(0)
+			break
+		}
+		ops |= sub.filter.Ops
+	}
+	atomic.StoreUint32(&hub.ops, uint32(ops))
+}
+
+//是否有订阅者关注op类型的事件
+func (hub *eventHub) wants(op EventOp) bool {
+	return atomic.LoadUint32(&hub.ops)&uint32(op) != 0
+}
+
+func (hub *eventHub) add(sub *Subscription) {
+	hub.mtx.Lock()
+	defer hub.mtx.Unlock()
+	if hub.subs == nil {
+		hub.subs = map[*Subscription]struct{}{}
+	}
+	hub.subs[sub] = struct{}{}
+	hub.updateOps()
+}
+
+func (hub *eventHub) remove(sub *Subscription) {
+	hub.mtx.Lock()
+	defer hub.mtx.Unlock()
+	if _, ok := hub.subs[sub]; !ok {
+		return
+	}
+	delete(hub.subs, sub)
+	hub.updateOps()
+	close(sub.ch)
+}
+
+//发布事件并补充命名空间和标签,没有订阅者关注该类型时直接返回
+func (minic *minicache) publish(op EventOp, k string, item Item) {
+	if !minic.events.wants(op) {
+		return
+	}
+	e := Event{Op: op, Key: k, Object: item.Object, Expiration: item.Expiration, Source: item.Source, Time: time.Now()}
+	if minic.nsMetrics != nil {
+		e.Namespace = minic.nsMetrics.lookup(k).prefix
+	}
+	if minic.eventTags != nil {
+		e.Tags = minic.eventTags(k, item.Object)
+	}
+	minic.events.deliver(e)
+}
+
+//把事件投递给匹配的订阅者
+func (hub *eventHub) deliver(e Event) {
+	hub.mtx.RLock()
+	defer hub.mtx.RUnlock()
+	for sub := range hub.subs {
+		if sub.filter.match(e) {
+			sub.deliver(e)
+		}
+	}
+}
+
+//订阅缓存事件,buffer为订阅者独立的缓冲区大小
+func (minic *minicache) Subscribe(filter EventFilter, buffer int, policy BufferPolicy) *Subscription {
+	return minic.events.subscribe(filter, buffer, policy)
+}
+
+func (hub *eventHub) subscribe(filter EventFilter, buffer int, policy BufferPolicy) *Subscription {
+	if buffer < 1 {
+		buffer = 1
+	}
+	sub := &Subscription{
+		hub:    hub,
+		filter: filter,
+		policy: policy,
+		ch:     make(chan Event, buffer),
+	}
+	hub.add(sub)
+	return sub
+}
+
+//分片共用同一个事件分发,订阅一次即可收到所有分片的事件
+func withEventHub(hub *eventHub) Option {
+	return func(minic *Minicache) {
+		minic.events = hub
+	}
+}
@@ -0,0 +1,91 @@
+package minicache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventFilterMatch(t *testing.T) {
+	e := Event{Op: EventSet, Key: "user:1", Namespace: "user:", Tags: []string{"hot", "json"}}
+	tests := []struct {
+		name   string
+		filter EventFilter
+		want   bool
+	}{
+		{"Zero", EventFilter{}, true},
+		{"Op", EventFilter{Ops: EventSet | EventDelete}, true},
+		{"OtherOp", EventFilter{Ops: EventDelete}, false},
+		{"Prefix", EventFilter{Prefix: "user:"}, true},
+		{"OtherPrefix", EventFilter{Prefix: "order:"}, false},
+		{"Key", EventFilter{Key: "user:1"}, true},
+		{"OtherKey", EventFilter{Key: "user:2"}, false},
+		{"Namespace", EventFilter{Namespace: "user:"}, true},
+		{"OtherNamespace", EventFilter{Namespace: "order:"}, false},
+		{"Tag", EventFilter{Tag: "json"}, true},
+		{"OtherTag", EventFilter{Tag: "cold"}, false},
+		{"All", EventFilter{Ops: EventSet, Prefix: "user", Namespace: "user:", Tag: "hot"}, true},
+		{"AllButTag", EventFilter{Ops: EventSet, Prefix: "user", Namespace: "user:", Tag: "cold"}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.filter.match(e); got != tc.want {
+				t.Fatalf("%+v.match() = %v, want %v", tc.filter, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSubscribeNamespaceAndTag(t *testing.T) {
+	c := NewMiniCache(0, 0, WithNamespaceMetrics("user:", "user:admin:"), WithEventTags(func(k string, v interface{}) []string {
+		if _, ok := v.(int); ok {
+			return []string{"int"}
+		}
+		return nil
+	}))
+	defer c.Close()
+	users := c.Subscribe(EventFilter{Ops: EventSet, Namespace: "user:"}, 10, DropNewest)
+	ints := c.Subscribe(EventFilter{Ops: EventSet, Tag: "int"}, 10, DropNewest)
+	c.Set("user:1", "a", 0)
+	c.Set("user:admin:1", 1, 0)
+	c.Set("order:1", 2, 0)
+	if got := drain(users); len(got) != 1 || got[0].Key != "user:1" || got[0].Namespace != "user:" {
+		t.Fatalf("namespace subscription received %v", got)
+	}
+	if got := drain(ints); len(got) != 2 || got[0].Key != "user:admin:1" || got[1].Key != "order:1" {
+		t.Fatalf("tag subscription received %v", got)
+	}
+	users.Unsubscribe()
+	users.Unsubscribe()
+	if _, ok := <-users.Events(); ok {
+		t.Fatal("Events() still open after Unsubscribe")
+	}
+}
+
+func TestSubscribeBufferPolicy(t *testing.T) {
+	c := NewMiniCache(0, 0)
+	defer c.Close()
+	newest := c.Subscribe(EventFilter{Ops: EventSet}, 2, DropNewest)
+	oldest := c.Subscribe(EventFilter{Ops: EventSet}, 2, DropOldest)
+	for _, k := range []string{"a", "b", "c", "d"} {
+		c.Set(k, 1, 0)
+	}
+	if got := drain(newest); len(got) != 2 || got[0].Key != "a" || got[1].Key != "b" || newest.Dropped() != 2 {
+		t.Fatalf("DropNewest kept %v, dropped %d", got, newest.Dropped())
+	}
+	if got := drain(oldest); len(got) != 2 || got[0].Key != "c" || got[1].Key != "d" || oldest.Dropped() != 2 {
+		t.Fatalf("DropOldest kept %v, dropped %d", got, oldest.Dropped())
+	}
+}
+
+//读出订阅缓冲区中已有的事件
+func drain(sub *Subscription) []Event {
+	var events []Event
+	for {
+		select {
+		case e := <-sub.Events():
+			events = append(events, e)
+		case <-time.After(10 * time.Millisecond):
+			return events
+		}
+	}
+}
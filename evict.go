@@ -0,0 +1,288 @@
+package minicache
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+)
+
+//淘汰策略,由evictor加锁后调用
+type evictionPolicy interface {
+	add(k string)           //写入键,已存在时视为一次访问
+	access(k string)        //键被读取命中,键不存在时忽略
+	remove(k string)        //键被删除
+	victim() (string, bool) //选出下一个淘汰的键,不从策略中移除
+	reset()
+}
+
+//访问只需读锁的淘汰策略,accessShared可与其他accessShared并发执行
+type sharedAccessPolicy interface {
+	accessShared(k string)
+}
+
+//淘汰时需要保留记录的淘汰策略,例如ARC的幽灵队列;没有实现时淘汰与删除相同,都调用remove
+type evictionAwarePolicy interface {
+	evicted(k string) //键因容量限制被淘汰
+}
+
+//数据项数量上限与淘汰策略
+//Get只持有读锁,访问记录由evictor自己的锁保护;加锁顺序为缓存锁在前
+type evictor struct {
+	mtx    sync.RWMutex
+	policy *priorityTiers
+	shared bool      //基础策略支持读锁下记录访问
+	sketch *cmSketch //开启TinyLFU准入时记录访问频率
+}
+
+//淘汰策略类型
+type EvictionPolicy int
+
+const (
+	EvictLRU    EvictionPolicy = iota //最近最少使用
+	EvictLFU                          //最不经常使用,访问频率定期衰减
+	EvictARC                          //自适应替换,在最近性与频率之间自动平衡
+	EvictSLRU                         //分段LRU,再次命中才进入受保护段
+	EvictCLOCK                        //时钟置换,近似LRU,命中时只设置访问位
+	EvictRandom                       //随机淘汰,不记录访问
+	EvictReject                       //不淘汰,缓存已满时写入新数据返回ErrCacheFull
+)
+
+//限制数据项数量,超过n时按淘汰策略淘汰,默认EvictLRU
+func WithMaxEntries(n int) Option {
+	return func(minic *Minicache) {
+		minic.maxEntries = n
+	}
+}
+
+//选择WithMaxEntries使用的淘汰策略
+func WithEvictionPolicy(p EvictionPolicy) Option {
+	return func(minic *Minicache) {
+		minic.evictionPolicy = p
+	}
+}
+
+//只按开销限制时淘汰策略与准入过滤按该数量估计容量
+const defaultPolicyCapacity = 1 << 16
+
+//capacity为预期的数据项数量,用于确定频率衰减周期、ARC队列长度和计数器规模
+func newEvictor(p EvictionPolicy, capacity int, admission bool) *evictor {
+	if capacity <= 0 {
+		capacity = defaultPolicyCapacity
+	}
+	ev := &evictor{}
+	if admission {
+		ev.sketch = newCMSketch(capacity)
+	}
+	newPolicy := func() evictionPolicy {
+		switch p {
+		case EvictLFU:
+			return newLFU(lfuDecayFactor * capacity)
+		case EvictARC:
+			return newARC(capacity)
+		case EvictSLRU:
+			return newSLRU(capacity)
+		case EvictCLOCK:
+			return newClock()
+		case EvictRandom:
+			return newRandomPolicy()
+		}
+		return newLRU()
+	}
+	ev.policy = newPriorityTiers(newPolicy)
+	_, ev.shared = ev.policy.tiers[0].(sharedAccessPolicy)
+	return ev
+}
+
+func (ev *evictor) add(k string, priority int) {
+	ev.mtx.Lock()
+	ev.policy.add(k, priority)
+	if ev.sketch != nil {
+		ev.sketch.increment(hashKey(k))
+	}
+	ev.mtx.Unlock()
+}
+
+func (ev *evictor) access(k string) {
+	if ev.shared && ev.sketch == nil {
+		ev.mtx.RLock()
+		ev.policy.accessShared(k)
+		ev.mtx.RUnlock()
+		return
+	}
+	ev.mtx.Lock()
+	ev.policy.access(k)
+	if ev.sketch != nil {
+		ev.sketch.increment(hashKey(k))
+	}
+	ev.mtx.Unlock()
+}
+
+//记录未命中的访问,只影响准入频率
+func (ev *evictor) miss(k string) {
+	if ev.sketch == nil {
+		return
+	}
+	ev.mtx.Lock()
+	ev.sketch.increment(hashKey(k))
+	ev.mtx.Unlock()
+}
+
+//新键的估计频率高于待淘汰键时才准入
+func (ev *evictor) admit(candidate, victim string) bool {
+	if ev.sketch == nil {
+		return true
+	}
+	ev.mtx.Lock()
+	defer ev.mtx.Unlock()
+	return ev.sketch.estimate(hashKey(candidate)) > ev.sketch.estimate(hashKey(victim))
+}
+
+func (ev *evictor) remove(k string) {
+	ev.mtx.Lock()
+	ev.policy.remove(k)
+	ev.mtx.Unlock()
+}
+
+func (ev *evictor) evicted(k string) {
+	ev.mtx.Lock()
+	ev.policy.evicted(k)
+	ev.mtx.Unlock()
+}
+
+func (ev *evictor) victim() (string, bool) {
+	ev.mtx.Lock()
+	defer ev.mtx.Unlock()
+	return ev.policy.victim()
+}
+
+func (ev *evictor) reset() {
+	ev.mtx.Lock()
+	ev.policy.reset()
+	if ev.sketch != nil {
+		ev.sketch.reset()
+	}
+	ev.mtx.Unlock()
+}
+
+//数据项数量或总开销超过上限时淘汰,keep为刚写入的键,优先保留
+//keep是未固定的新键且未通过准入,或者keep自身的开销超过上限时改为删除keep,无锁
+//删除keep与淘汰其他键一样以EventEvict通知,写入方法仍返回nil:写入已经生效,只是随即被淘汰
+//被固定的键不在淘汰策略中,全部剩余数据项都被固定时允许超过上限
+func (minic *minicache) evict(keep string, isNew bool) {
+	candidate := !minic.pinned(keep)
+	if item, _ := minic.items.get(keep); candidate && minic.maxCost > 0 && item.cost > minic.maxCost {
+		minic.delete(keep, EventEvict)
+		return
+	}
+	ev := minic.evictor.Load()
+	retried := false
+	for minic.overCapacity() {
+		k, ok := ev.victim()
+		if !ok {
+			return
+		}
+		if k == keep {
+			//新键可能还没有访问记录(例如CLOCK的访问位为0),视为一次访问后重新选择
+			//仍然选中keep说明它是最应淘汰的键(例如所在优先级最低),淘汰keep
+			if retried {
+				minic.delete(keep, EventEvict)
+				return
+			}
+			retried = true
+			ev.access(keep)
+			continue
+		}
+		retried = false
+		if candidate && isNew && !ev.admit(keep, k) {
+			minic.admissionRejects++
+			minic.delete(keep, EventEvict)
+			return
+		}
+		minic.delete(k, EventEvict)
+	}
+}
+
+//拒绝模式下检查写入后是否超过容量,超过时先删除已到期的数据项,仍然超过则返回ErrCacheFull
+//覆盖已有的键不增加数据项数量,但开销增加同样受上限约束,无锁
+func (minic *minicache) reserve(k string, item *Item) error {
+	if minic.evictionPolicy != EvictReject || (minic.maxEntries <= 0 && minic.maxCost <= 0) {
+		return nil
+	}
+	if item.cost <= 0 {
+		item.cost = minic.costOf(k, item.Object)
+	}
+	if !minic.wouldOverflow(k, item.cost) {
+		return nil
+	}
+	if minic.gcSamples == 0 {
+		//OnExpired回调留到释放写锁后执行
+		b := minic.deleteDue(time.Now().UnixNano()-int64(minic.expiredRetention)-1, minic.gcBatchSize, minic.onExpired != nil)
+		minic.expiredPending = append(minic.expiredPending, b.expired...)
+	}
+	if minic.wouldOverflow(k, item.cost) {
+		return fmt.Errorf("%w: %s", ErrCacheFull, k)
+	}
+	return nil
+}
+
+//写入开销为cost的键k后是否超过容量,无锁
+func (minic *minicache) wouldOverflow(k string, cost int64) bool {
+	old, found := minic.items.get(k)
+	n := minic.items.len()
+	if !found {
+		n++
+	}
+	return (minic.maxEntries > 0 && n > minic.maxEntries) ||
+		(minic.maxCost > 0 && minic.totalCost-old.cost+cost > minic.maxCost)
+}
+
+//是否超过数据项数量或总开销上限,无锁
+func (minic *minicache) overCapacity() bool {
+	return (minic.maxEntries > 0 && minic.items.len() > minic.maxEntries) ||
+		(minic.maxCost > 0 && minic.totalCost > minic.maxCost)
+}
+
+//最近最少使用
+type lru struct {
+	order *list.List //表头为最近访问
+	elems map[string]*list.Element
+}
+
+func newLRU() *lru {
+	return &lru{order: list.New(), elems: map[string]*list.Element{}}
+}
+
+func (p *lru) add(k string) {
+	if e, ok := p.elems[k]; ok {
+		p.order.MoveToFront(e)
+		return
+	}
+	p.elems[k] = p.order.PushFront(k)
+}
+
+func (p *lru) access(k string) {
+	if e, ok := p.elems[k]; ok {
+		p.order.MoveToFront(e)
+	}
+}
+
+func (p *lru) remove(k string) {
+	if e, ok := p.elems[k]; ok {
+		p.order.Remove(e)
+		delete(p.elems, k)
+	}
+}
+
+func (p *lru) victim() (string, bool) {
+	e := p.order.Back()
+	if e == nil {
+		return "", false
+	}
+	return e.Value.(string), true
+}
+
+func (p *lru) reset() {
+	p.order.Init()
+	p.elems = map[string]*list.Element{}
+}
@@ -0,0 +1,156 @@
+package minicache
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMaxEntriesPolicies(t *testing.T) {
+	policies := []struct {
+		name string
+		p    EvictionPolicy
+	}{
+		{"LRU", EvictLRU},
+		{"LFU", EvictLFU},
+		{"ARC", EvictARC},
+		{"SLRU", EvictSLRU},
+		{"CLOCK", EvictCLOCK},
+		{"Random", EvictRandom},
+	}
+	for _, tc := range policies {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewMiniCache(0, 0, WithMaxEntries(10), WithEvictionPolicy(tc.p))
+			defer c.Close()
+			for i := 0; i < 1000; i++ {
+				k := fmt.Sprint("k", i)
+				c.Set(k, i, 0)
+				c.Get(k)
+				if n := c.Count(); n > 10 {
+					t.Fatalf("after %d writes Count() = %d, want <= 10", i+1, n)
+				}
+			}
+		})
+	}
+}
+
+func TestEvictLRUOrder(t *testing.T) {
+	c := NewMiniCache(0, 0, WithMaxEntries(3))
+	defer c.Close()
+	c.Set("a", 1, 0)
+	c.Set("b", 2, 0)
+	c.Set("c", 3, 0)
+	c.Get("a")
+	c.Set("d", 4, 0)
+	if _, found := c.Get("b"); found {
+		t.Fatal("least recently used key b was not evicted")
+	}
+	for _, k := range []string{"a", "c", "d"} {
+		if _, found := c.Get(k); !found {
+			t.Fatalf("key %s was evicted", k)
+		}
+	}
+}
+
+func TestPriorityEvictsLowerTierFirst(t *testing.T) {
+	c := NewMiniCache(0, 0, WithMaxEntries(10))
+	defer c.Close()
+	for i := 0; i < 10; i++ {
+		c.SetWithPriority(fmt.Sprint("high", i), i, 0, 10)
+	}
+	for i := 0; i < 5; i++ {
+		c.SetWithPriority(fmt.Sprint("low", i), i, 0, 0)
+		if n := c.Count(); n != 10 {
+			t.Fatalf("Count() = %d, want 10", n)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		if _, found := c.Get(fmt.Sprint("high", i)); !found {
+			t.Fatalf("high priority key %d was evicted before a low priority key", i)
+		}
+	}
+	c.SetWithPriority("higher", 0, 0, 20)
+	if _, found := c.Get("higher"); !found || c.Count() != 10 {
+		t.Fatalf("higher priority key not kept, Count() = %d", c.Count())
+	}
+}
+
+func TestPriorityEvictsLowestTierNewKey(t *testing.T) {
+	var evicted []string
+	c := NewMiniCache(0, 0, WithMaxEntries(2))
+	defer c.Close()
+	c.OnEvicted(func(k string, v interface{}, reason EvictionReason) {
+		if reason == ReasonEvicted {
+			evicted = append(evicted, k)
+		}
+	})
+	events := c.Subscribe(EventFilter{Ops: EventEvict}, 10, DropNewest)
+	c.SetWithPriority("a", 1, 0, 10)
+	c.SetWithPriority("b", 2, 0, 10)
+	if err := c.SetWithPriority("low", 3, 0, 0); err != nil {
+		t.Fatalf("SetWithPriority(low) = %v, want nil", err)
+	}
+	if _, found := c.Get("low"); found {
+		t.Fatal("lowest priority new key was kept")
+	}
+	for _, k := range []string{"a", "b"} {
+		if _, found := c.Get(k); !found {
+			t.Fatalf("key %s was evicted instead of the lowest priority new key", k)
+		}
+	}
+	if len(evicted) != 1 || evicted[0] != "low" {
+		t.Fatalf("OnEvicted received %v, want [low]", evicted)
+	}
+	if got := drain(events); len(got) != 1 || got[0].Key != "low" {
+		t.Fatalf("EventEvict received %v, want low", got)
+	}
+}
+
+func TestRejectFreesExpiredWithCallback(t *testing.T) {
+	c := NewMiniCache(0, 0, WithMaxEntries(2), WithEvictionPolicy(EvictReject))
+	defer c.Close()
+	var expired []string
+	c.OnExpired(func(k string, v interface{}) {
+		//回调在锁外执行,可以访问缓存
+		c.Count()
+		expired = append(expired, k)
+	})
+	c.Set("a", 1, time.Millisecond)
+	c.Set("b", 2, NoExpiration)
+	time.Sleep(5 * time.Millisecond)
+	if err := c.Set("c", 3, NoExpiration); err != nil {
+		t.Fatalf("Set(c) = %v, want the expired item to make room", err)
+	}
+	if len(expired) != 1 || expired[0] != "a" {
+		t.Fatalf("OnExpired saw %v, want [a]", expired)
+	}
+	if err := c.Set("d", 4, NoExpiration); !errors.Is(err, ErrCacheFull) {
+		t.Fatalf("Set(d) = %v, want ErrCacheFull", err)
+	}
+}
+
+func TestARCGhostsOnlyOnEviction(t *testing.T) {
+	c := NewMiniCache(0, 0, WithMaxEntries(2), WithEvictionPolicy(EvictARC))
+	defer c.Close()
+	arc := c.evictor.Load().policy.tiers[0].(*arc)
+	c.Set("a", 1, 0)
+	c.Set("b", 2, 10*time.Millisecond)
+	c.Delete("a")
+	time.Sleep(20 * time.Millisecond)
+	c.DeleteExpired()
+	if n := len(arc.entries); n != 0 {
+		t.Fatalf("deleted and expired keys left %d ARC entries", n)
+	}
+	c.Set("a", 1, 0)
+	if e := arc.entries["a"]; e == nil || e.where != arcT1 {
+		t.Fatal("key written again after Delete was not treated as new")
+	}
+	c.Set("b", 2, 0)
+	c.Get("a")
+	c.Get("b")
+	c.Set("c", 3, 0)
+	if e := arc.entries["a"]; e == nil || e.where != arcB2 {
+		t.Fatal("evicted key was not kept as a B2 ghost")
+	}
+}
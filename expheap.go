@@ -0,0 +1,140 @@
+package minicache
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+//过期堆节点,过期时间相同的键合并到同一节点
+type expiryNode struct {
+	expiration int64
+	keys       []string
+}
+
+type expiryNodes []*expiryNode
+
+func (h expiryNodes) Len() int            { return len(h) }
+func (h expiryNodes) Less(i, j int) bool  { return h[i].expiration < h[j].expiration }
+func (h expiryNodes) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *expiryNodes) Push(x interface{}) { *h = append(*h, x.(*expiryNode)) }
+func (h *expiryNodes) Pop() interface{} {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return e
+}
+
+//按过期时间排序的最小堆
+//数据项的过期时间变化时只追加新节点,旧节点在弹出时与数据项比对后丢弃
+//开启过期时间分桶后大量数据项共享节点,堆的规模随桶数而不是数据项数增长
+type expiryHeap struct {
+	nodes expiryNodes
+	byExp map[int64]*expiryNode
+	size  int
+}
+
+//登记过期时间为e的键
+func (h *expiryHeap) push(e int64, k string) {
+	h.size++
+	if node, ok := h.byExp[e]; ok {
+		node.keys = append(node.keys, k)
+		return
+	}
+	if h.byExp == nil {
+		h.byExp = map[int64]*expiryNode{}
+	}
+	node := expiryNodePool.Get().(*expiryNode)
+	node.expiration = e
+	node.keys = append(node.keys, k)
+	h.byExp[e] = node
+	heap.Push(&h.nodes, node)
+}
+
+//堆顶过期时间不晚于deadline时弹出堆顶节点
+func (h *expiryHeap) popDue(deadline int64) (*expiryNode, bool) {
+	if len(h.nodes) == 0 || h.nodes[0].expiration > deadline {
+		return nil, false
+	}
+	node := heap.Pop(&h.nodes).(*expiryNode)
+	delete(h.byExp, node.expiration)
+	h.size -= len(node.keys)
+	return node, true
+}
+
+//高频写入和过期时复用堆节点及其键切片,减少内存分配
+var expiryNodePool = sync.Pool{New: func() interface{} { return &expiryNode{} }}
+
+//键数量超过该值的节点不放回池中,避免长期占用大块内存
+const maxPooledNodeKeys = 1024
+
+//popDue弹出的节点处理完后放回池中,之后不能再使用node
+func (h *expiryHeap) release(node *expiryNode) {
+	if cap(node.keys) > maxPooledNodeKeys {
+		return
+	}
+	clear(node.keys)
+	node.keys = node.keys[:0]
+	expiryNodePool.Put(node)
+}
+
+//登记的键数量,包括已失效的
+func (h *expiryHeap) len() int {
+	return h.size
+}
+
+func (h *expiryHeap) reset() {
+	*h = expiryHeap{}
+}
+
+//登记数据项的过期时间,无锁
+func (minic *minicache) schedule(k string, e int64) {
+	if e == 0 {
+		return
+	}
+	//与存储的过期时间一致,gc按相等判断节点是否仍然有效
+	e = minic.items.round(e)
+	if minic.gcSamples == 0 {
+		minic.expiries.push(e, k)
+	}
+	if minic.expiryNotice > 0 {
+		minic.notices.push(e-int64(minic.expiryNotice), k)
+	}
+	//失效节点过多时按当前数据项重建
+	if n := minic.expiries.len() + minic.notices.len(); n > 1024 && n > 2*minic.items.len() {
+		minic.rebuildExpiries()
+	}
+}
+
+//按当前数据项重建过期堆,无锁
+func (minic *minicache) rebuildExpiries() {
+	minic.expiries.reset()
+	minic.notices.reset()
+	now := time.Now().UnixNano()
+	minic.items.each(func(k string, v Item) bool {
+		if v.Expiration == 0 {
+			return true
+		}
+		if minic.gcSamples == 0 {
+			minic.expiries.push(v.Expiration, k)
+		}
+		if minic.expiryNotice > 0 && v.Expiration > now {
+			minic.notices.push(v.Expiration-int64(minic.expiryNotice), k)
+		}
+		return true
+	})
+}
+
+//按分桶向上取整过期时间,保证数据项不会提前过期;开启WithCoarseExpiration时再取整到整秒
+func (minic *minicache) align(e int64) int64 {
+	b := int64(minic.expirationBucket)
+	if b <= 0 || e == 0 {
+		return minic.items.round(e)
+	}
+	if r := e % b; r != 0 {
+		e += b - r
+	}
+	return minic.items.round(e)
+}
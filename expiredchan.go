@@ -0,0 +1,40 @@
+package minicache
+
+import "time"
+
+//数据项过期事件
+type ExpiredEvent struct {
+	Key       string
+	Value     interface{}
+	ExpiredAt time.Time //数据项的过期时间,而不是被删除的时间
+}
+
+//返回过期事件通道,gc、访问时的惰性删除和写入清理删除过期数据项时投递事件
+//缓冲区大小为buffer,写满时丢弃新事件,不会阻塞gc;缓存Close后通道关闭
+func (minic *minicache) ExpiredChan(buffer int) <-chan ExpiredEvent {
+	return minic.events.expiredChan(buffer, minic.done)
+}
+
+//done关闭后取消订阅并关闭返回的通道
+func (hub *eventHub) expiredChan(buffer int, done <-chan struct{}) <-chan ExpiredEvent {
+	sub := hub.subscribe(EventFilter{Ops: EventExpire}, buffer, DropNewest)
+	out := make(chan ExpiredEvent)
+	go func() {
+		defer close(out)
+		defer sub.Unsubscribe()
+		for {
+			var e Event
+			select {
+			case e = <-sub.Events():
+			case <-done:
+				return
+			}
+			select {
+			case out <- ExpiredEvent{Key: e.Key, Value: e.Object, ExpiredAt: time.Unix(0, e.Expiration)}:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return out
+}
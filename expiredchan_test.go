@@ -0,0 +1,36 @@
+package minicache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpiredChan(t *testing.T) {
+	c := NewMiniCache(0, 0)
+	ch := c.ExpiredChan(10)
+	c.Set("a", 1, 10*time.Millisecond)
+	c.Set("b", 2, 0)
+	item, _ := c.Inspect("a")
+	time.Sleep(20 * time.Millisecond)
+	if n := c.DeleteExpired(); n != 1 {
+		t.Fatalf("DeleteExpired() = %d, want 1", n)
+	}
+	select {
+	case e := <-ch:
+		if e.Key != "a" || e.Value != 1 || !e.ExpiredAt.Equal(time.Unix(0, item.Expiration)) {
+			t.Fatalf("ExpiredChan delivered %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ExpiredChan did not deliver the expired item")
+	}
+	c.Delete("b")
+	c.Close()
+	select {
+	case e, ok := <-ch:
+		if ok {
+			t.Fatalf("ExpiredChan delivered %+v for a deleted item", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ExpiredChan was not closed after Close")
+	}
+}
@@ -0,0 +1,31 @@
+package minicache
+
+import "time"
+
+//每次写入时顺带删除至多n个已过期的数据项
+//与非正的gcInterval配合使用时缓存不启动任何后台goroutine,过期数据项只在读取和写入时被清理
+func WithExpireOnWrite(n int) Option {
+	return func(minic *Minicache) {
+		minic.expireOnWrite = n
+	}
+}
+
+//释放写锁,开启写入清理时先删除少量到期数据项,OnExpired和OnEvicted回调在锁外执行
+func (minic *minicache) writeUnlock() {
+	if minic.expireOnWrite <= 0 {
+		minic.unlock()
+		return
+	}
+	deadline := time.Now().UnixNano() - int64(minic.expiredRetention) - 1
+	onExpired := minic.onExpired
+	var b sweepBatch
+	if minic.gcSamples > 0 {
+		b = minic.deleteSampled(deadline, minic.expireOnWrite, onExpired != nil)
+	} else {
+		b = minic.deleteDue(deadline, minic.expireOnWrite, onExpired != nil)
+	}
+	minic.unlock()
+	for _, e := range b.expired {
+		onExpired(e.key, e.object)
+	}
+}
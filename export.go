@@ -0,0 +1,129 @@
+package minicache
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+//导出时类型转换失败的键
+type ExportError struct {
+	Keys  []string //类型无法转换
+	Lossy []string //数值转换会截断小数、溢出、改变符号或丢失精度
+}
+
+func (e *ExportError) Error() string {
+	var parts []string
+	if len(e.Keys) > 0 {
+		parts = append(parts, fmt.Sprintf("%d items could not be converted: %s", len(e.Keys), strings.Join(e.Keys, ", ")))
+	}
+	if len(e.Lossy) > 0 {
+		parts = append(parts, fmt.Sprintf("%d items would lose precision: %s", len(e.Lossy), strings.Join(e.Lossy, ", ")))
+	}
+	return strings.Join(parts, "; ")
+}
+
+//导出前缀为prefix的未过期数据项,并转换为T类型
+//无法直接断言的值在数值类型之间或相同底层类型之间做转换,其余转换失败的键通过*ExportError返回,
+//数值转换只在不损失信息时进行,例如int64(300)不会转换为int8,2.5不会转换为int,这些键记录在ExportError.Lossy中;
+//转换成功的数据项仍会返回
+func ExportTyped[T any](c *Minicache, prefix string) (map[string]T, error) {
+	now := time.Now().UnixNano()
+	c.rwmtx.RLock()
+	snapshot := make(map[string]interface{})
+	c.items.each(func(k string, v Item) bool {
+		if strings.HasPrefix(k, prefix) && (v.Expiration == 0 || now <= v.Expiration) {
+			snapshot[k] = v.Object
+		}
+		return true
+	})
+	c.rwmtx.RUnlock()
+
+	result := make(map[string]T, len(snapshot))
+	var failed, lossy []string
+	target := reflect.TypeOf((*T)(nil)).Elem()
+	for k, obj := range snapshot {
+		if v, ok := obj.(T); ok {
+			result[k] = v
+			continue
+		}
+		v, ok, exact := convertTo(obj, target)
+		switch {
+		case !ok:
+			failed = append(failed, k)
+		case !exact:
+			lossy = append(lossy, k)
+		default:
+			result[k] = v.Interface().(T)
+		}
+	}
+	if len(failed) > 0 || len(lossy) > 0 {
+		sort.Strings(failed)
+		sort.Strings(lossy)
+		return result, &ExportError{Keys: failed, Lossy: lossy}
+	}
+	return result, nil
+}
+
+//数值类型之间或底层类型相同时才做转换,避免int转string这类意外结果
+//exact表示转换没有损失信息:转换回原类型后与原值相等且符号不变
+func convertTo(obj interface{}, target reflect.Type) (out reflect.Value, ok, exact bool) {
+	if obj == nil {
+		return reflect.Value{}, false, false
+	}
+	v := reflect.ValueOf(obj)
+	if !v.Type().ConvertibleTo(target) {
+		return reflect.Value{}, false, false
+	}
+	if v.Kind() != target.Kind() && !(isNumeric(v.Kind()) && isNumeric(target.Kind())) {
+		return reflect.Value{}, false, false
+	}
+	out = v.Convert(target)
+	if !isNumeric(v.Kind()) {
+		return out, true, true
+	}
+	return out, true, exactConversion(v, out)
+}
+
+func exactConversion(in, out reflect.Value) bool {
+	if isFloat(in.Kind()) && math.IsNaN(in.Float()) {
+		return isFloat(out.Kind())
+	}
+	//float转int超出范围的结果由实现决定,先按范围判断
+	if isFloat(in.Kind()) && !isFloat(out.Kind()) && !fitsInt(in.Float(), out.Type()) {
+		return false
+	}
+	if isNegative(in) != isNegative(out) {
+		return false
+	}
+	return out.Convert(in.Type()).Interface() == in.Interface()
+}
+
+func isNegative(v reflect.Value) bool {
+	switch {
+	case v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64:
+		return v.Int() < 0
+	case isFloat(v.Kind()):
+		return v.Float() < 0
+	}
+	return false
+}
+
+//f在转换为整数类型t时不溢出
+func fitsInt(f float64, t reflect.Type) bool {
+	if k := t.Kind(); k >= reflect.Uint && k <= reflect.Uintptr {
+		return f > -1 && f < math.Ldexp(1, t.Bits())
+	}
+	return f >= -math.Ldexp(1, t.Bits()-1) && f < math.Ldexp(1, t.Bits()-1)
+}
+
+func isFloat(k reflect.Kind) bool {
+	return k == reflect.Float32 || k == reflect.Float64
+}
+
+func isNumeric(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Float64
+}
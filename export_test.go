@@ -0,0 +1,58 @@
+package minicache
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestExportTypedConversions(t *testing.T) {
+	type celsius float64
+	for name, tc := range map[string]struct {
+		v      interface{}
+		target interface{}
+		lossy  bool
+	}{
+		"int64 to int8":         {int64(100), int8(100), false},
+		"int64 overflows int8":  {int64(300), int8(0), true},
+		"negative to uint":      {-1, uint(0), true},
+		"whole float to int":    {float64(42), 42, false},
+		"fraction to int":       {2.5, 0, true},
+		"float beyond int64":    {1e30, int64(0), true},
+		"float64 to float32":    {0.5, float32(0.5), false},
+		"precision to float32":  {0.1, float32(0), true},
+		"big int to float64":    {int64(1<<53 + 1), float64(0), true},
+		"named underlying type": {celsius(36.6), 36.6, false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			target := reflect.TypeOf(tc.target)
+			out, ok, exact := convertTo(tc.v, target)
+			if !ok || exact == tc.lossy {
+				t.Fatalf("convertTo(%v, %v) = ok %v, exact %v; want lossy = %v", tc.v, target, ok, exact, tc.lossy)
+			}
+			if !tc.lossy && out.Interface() != tc.target {
+				t.Fatalf("convertTo(%v, %v) = %v, want %v", tc.v, target, out, tc.target)
+			}
+		})
+	}
+}
+
+func TestExportTypedReportsLossy(t *testing.T) {
+	c := NewMiniCache(0, 0)
+	defer c.Close()
+	c.Set("n:ok", int64(7), 0)
+	c.Set("n:big", int64(math.MaxInt32)+1, 0)
+	c.Set("n:str", "seven", 0)
+	got, err := ExportTyped[int32](c, "n:")
+	var ee *ExportError
+	if !errors.As(err, &ee) {
+		t.Fatalf("ExportTyped() error = %v, want *ExportError", err)
+	}
+	if !reflect.DeepEqual(ee.Keys, []string{"n:str"}) || !reflect.DeepEqual(ee.Lossy, []string{"n:big"}) {
+		t.Fatalf("ExportError = %+v", ee)
+	}
+	if len(got) != 1 || got["n:ok"] != 7 {
+		t.Fatalf("ExportTyped() = %v", got)
+	}
+}
@@ -0,0 +1,19 @@
+package minicache
+
+import "sync/atomic"
+
+//冻结缓存,之后所有写操作返回ErrReadOnly,不能解除
+//冻结时等待异步写入完成并停止gc;冻结后过期数据项只是读不到,不会被删除,滑动过期不再顺延
+//冻结后数据不再变化,Get读取时不加锁
+func (minic *minicache) Freeze() {
+	minic.Wait()
+	minic.rwmtx.Lock()
+	atomic.StoreInt32(&minic.frozen, 1)
+	minic.rwmtx.Unlock()
+	minic.Stopgc()
+}
+
+//是否已冻结
+func (minic *minicache) Frozen() bool {
+	return atomic.LoadInt32(&minic.frozen) != 0
+}
@@ -0,0 +1,32 @@
+package minicache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetMultiTouchReadOnly(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		stop func(c *Minicache)
+	}{
+		{"Frozen", func(c *Minicache) { c.Freeze() }},
+		{"Closed", func(c *Minicache) { c.Close() }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewMiniCache(0, 0)
+			defer c.Close()
+			c.Set("a", 1, time.Hour)
+			c.Set("b", 2, time.Hour)
+			before, _ := c.Inspect("a")
+			tc.stop(c)
+			got := c.GetMultiTouch([]string{"a", "b", "missing"}, 2*time.Hour)
+			if len(got) != 2 || got["a"] != 1 || got["b"] != 2 {
+				t.Fatalf("GetMultiTouch() = %v, want a and b", got)
+			}
+			if after, _ := c.Inspect("a"); after.Expiration != before.Expiration {
+				t.Fatalf("expiration changed from %d to %d", before.Expiration, after.Expiration)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package minicache
+
+import (
+	"sync"
+	"time"
+)
+
+//一次过期清理的统计
+type GCStats struct {
+	Start       time.Time     //开始时间
+	Duration    time.Duration //总耗时,包括锁外执行回调的时间
+	Items       int           //开始时的数据项数量
+	Scanned     int           //检查过的键数量
+	Deleted     int           //删除的数量
+	Batches     int           //分批加锁的次数
+	LockHeld    time.Duration //持有写锁的总时长
+	MaxLockHeld time.Duration //单批持有写锁的最长时长
+}
+
+//最近一次清理的统计和回调
+type gcStatsRecorder struct {
+	mtx  sync.Mutex
+	last GCStats
+	fn   func(GCStats)
+}
+
+//返回最近一次过期清理的统计,尚未清理过时返回零值
+func (minic *minicache) GCStats() GCStats {
+	return minic.gcStats.get()
+}
+
+//注册清理统计回调,每次gc或DeleteExpired结束后在锁外调用,再次注册会替换之前的回调
+func (minic *minicache) OnGC(fn func(GCStats)) {
+	minic.gcStats.onGC(fn)
+}
+
+func (minic *minicache) recordGC(stats GCStats) {
+	minic.gcStats.record(stats)
+}
+
+func (r *gcStatsRecorder) get() GCStats {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.last
+}
+
+func (r *gcStatsRecorder) onGC(fn func(GCStats)) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.fn = fn
+}
+
+func (r *gcStatsRecorder) record(stats GCStats) {
+	r.mtx.Lock()
+	r.last = stats
+	fn := r.fn
+	r.mtx.Unlock()
+	if fn != nil {
+		fn(stats)
+	}
+}
+
+//累加另一个分片同一轮清理的统计,不修改开始时间和总耗时
+func (s *GCStats) add(o GCStats) {
+	s.Items += o.Items
+	s.Scanned += o.Scanned
+	s.Deleted += o.Deleted
+	s.Batches += o.Batches
+	s.LockHeld += o.LockHeld
+	s.MaxLockHeld = max(s.MaxLockHeld, o.MaxLockHeld)
+}
@@ -0,0 +1,161 @@
+package minicache
+
+import (
+	"container/heap"
+	"sort"
+	"sync"
+	"time"
+)
+
+//热点键及其在统计窗口内的估计访问次数
+type HotKey struct {
+	Key   string
+	Count uint64
+}
+
+//Space-Saving计数器
+type ssEntry struct {
+	key   string
+	count uint64
+	index int
+}
+
+type ssHeap []*ssEntry
+
+func (h ssHeap) Len() int           { return len(h) }
+func (h ssHeap) Less(i, j int) bool { return h[i].count < h[j].count }
+func (h ssHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *ssHeap) Push(x interface{}) {
+	e := x.(*ssEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+func (h *ssHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
+
+//用固定数量的计数器近似统计访问最多的键,计数器满时替换计数最小的键,新键继承其计数
+type spaceSaving struct {
+	entries map[string]*ssEntry
+	heap    ssHeap
+	size    int
+}
+
+func newSpaceSaving(size int) *spaceSaving {
+	return &spaceSaving{entries: make(map[string]*ssEntry, size), size: size}
+}
+
+func (s *spaceSaving) observe(k string) {
+	if e, ok := s.entries[k]; ok {
+		e.count++
+		heap.Fix(&s.heap, e.index)
+		return
+	}
+	if len(s.heap) < s.size {
+		e := &ssEntry{key: k, count: 1}
+		s.entries[k] = e
+		heap.Push(&s.heap, e)
+		return
+	}
+	e := s.heap[0]
+	delete(s.entries, e.key)
+	e.key = k
+	e.count++
+	s.entries[k] = e
+	heap.Fix(&s.heap, 0)
+}
+
+//热点键统计,当前和上一个半窗口各一组计数器,合并后近似最近一个窗口内的访问
+type hotKeys struct {
+	mtx     sync.Mutex
+	half    time.Duration
+	size    int
+	started time.Time
+	cur     *spaceSaving
+	prev    *spaceSaving
+}
+
+//统计最近window时长内访问最多的键,size为保留的计数器数量,TopKeys的结果不超过size个
+//window不大于0时从创建起累计统计
+func WithHotKeyTracking(window time.Duration, size int) Option {
+	return func(minic *Minicache) {
+		minic.hotKeys = &hotKeys{
+			half:    window / 2,
+			size:    size,
+			started: time.Now(),
+			cur:     newSpaceSaving(size),
+			prev:    newSpaceSaving(0),
+		}
+	}
+}
+
+func (h *hotKeys) observe(k string) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.rotate(time.Now())
+	h.cur.observe(k)
+}
+
+//半窗口结束时轮换计数器,需持有h.mtx
+func (h *hotKeys) rotate(now time.Time) {
+	elapsed := now.Sub(h.started)
+	if h.half <= 0 || elapsed < h.half {
+		return
+	}
+	if elapsed < 2*h.half {
+		h.prev = h.cur
+	} else {
+		h.prev = newSpaceSaving(0)
+	}
+	h.cur = newSpaceSaving(h.size)
+	h.started = now
+}
+
+//返回最近统计窗口内Get访问最多的n个键,按次数从多到少排序,未开启WithHotKeyTracking时返回nil
+//计数是近似值,可能偏高,访问量占比超过1/size的键一定会出现在结果中
+func (minic *minicache) TopKeys(n int) []HotKey {
+	if minic.hotKeys == nil {
+		return nil
+	}
+	counts := map[string]uint64{}
+	minic.hotKeys.countInto(counts)
+	return topHotKeys(counts, n)
+}
+
+//把最近统计窗口内的计数累加到counts
+func (h *hotKeys) countInto(counts map[string]uint64) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.rotate(time.Now())
+	for k, e := range h.prev.entries {
+		counts[k] += e.count
+	}
+	for k, e := range h.cur.entries {
+		counts[k] += e.count
+	}
+}
+
+//按次数从多到少取前n个键,n小于0时返回全部
+func topHotKeys(counts map[string]uint64, n int) []HotKey {
+	top := make([]HotKey, 0, len(counts))
+	for k, c := range counts {
+		top = append(top, HotKey{Key: k, Count: c})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Count != top[j].Count {
+			return top[i].Count > top[j].Count
+		}
+		return top[i].Key < top[j].Key
+	})
+	if n >= 0 && n < len(top) {
+		top = top[:n]
+	}
+	return top
+}
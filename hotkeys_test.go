@@ -0,0 +1,61 @@
+package minicache
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTopKeysOrder(t *testing.T) {
+	c := NewMiniCache(0, 0, WithHotKeyTracking(0, 10))
+	defer c.Close()
+	for k, n := range map[string]int{"a": 5, "b": 3, "c": 3, "d": 1} {
+		for i := 0; i < n; i++ {
+			c.Get(k)
+		}
+	}
+	want := []HotKey{{"a", 5}, {"b", 3}, {"c", 3}}
+	if got := c.TopKeys(3); !reflect.DeepEqual(got, want) {
+		t.Fatalf("TopKeys(3) = %v, want %v", got, want)
+	}
+	if got := c.TopKeys(-1); len(got) != 4 {
+		t.Fatalf("TopKeys(-1) returned %d keys, want 4", len(got))
+	}
+	plain := NewMiniCache(0, 0)
+	defer plain.Close()
+	if got := plain.TopKeys(3); got != nil {
+		t.Fatalf("TopKeys without tracking = %v, want nil", got)
+	}
+}
+
+//计数器满时新键替换计数最小的键,访问最多的键不会被替换
+func TestTopKeysSpaceSaving(t *testing.T) {
+	c := NewMiniCache(0, 0, WithHotKeyTracking(0, 2))
+	defer c.Close()
+	for i := 0; i < 10; i++ {
+		c.Get("hot")
+	}
+	for _, k := range []string{"x", "y", "z"} {
+		c.Get(k)
+	}
+	top := c.TopKeys(2)
+	if len(top) != 2 || top[0] != (HotKey{"hot", 10}) || top[1] != (HotKey{"z", 3}) {
+		t.Fatalf("TopKeys(2) = %v, want hot with 10 and z inheriting 3", top)
+	}
+}
+
+func TestTopKeysWindow(t *testing.T) {
+	c := NewMiniCache(0, 0, WithHotKeyTracking(100*time.Millisecond, 10))
+	defer c.Close()
+	c.Get("old")
+	time.Sleep(60 * time.Millisecond)
+	c.Get("new")
+	//上一个半窗口的计数仍在统计窗口内
+	if got := c.TopKeys(-1); len(got) != 2 {
+		t.Fatalf("TopKeys after half a window = %v, want old and new", got)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if got := c.TopKeys(-1); len(got) != 1 || got[0].Key != "new" {
+		t.Fatalf("TopKeys after a window = %v, want only new", got)
+	}
+}
@@ -0,0 +1,45 @@
+package minicache
+
+import "fmt"
+
+//值超过大小上限时写操作的处理方式
+type OversizePolicy int
+
+const (
+	OversizeReject OversizePolicy = iota //返回ErrValueTooLarge
+	OversizeSkip                         //不写入,也不返回错误
+)
+
+//限制单个值的大小,超过n字节的值按策略p拒绝或跳过,大小由WithSizer设置的Sizer估计,默认基于反射
+//Memoize和GetOrLoad加载的值超过上限时照常返回但不缓存,Load时跳过快照中超过上限的数据项
+func WithMaxItemSize(n int64, p OversizePolicy) Option {
+	return func(minic *Minicache) {
+		minic.maxItemSize = n
+		minic.oversizePolicy = p
+	}
+}
+
+//估计值的大小
+func (minic *minicache) sizeOf(v interface{}) int64 {
+	if minic.sizer != nil {
+		return minic.sizer.Size(v)
+	}
+	return reflectSizer{}.Size(v)
+}
+
+//判断值是否超过大小上限,超过时按策略返回写操作应返回的错误
+//OversizeSkip策略下返回(true, nil),调用方应直接返回
+func (minic *minicache) oversize(k string, v interface{}) (bool, error) {
+	if minic.maxItemSize <= 0 || minic.sizeOf(v) <= minic.maxItemSize {
+		return false, nil
+	}
+	return minic.oversized(k)
+}
+
+//按策略返回超过大小上限时应返回的错误
+func (minic *minicache) oversized(k string) (bool, error) {
+	if minic.oversizePolicy == OversizeSkip {
+		return true, nil
+	}
+	return true, fmt.Errorf("%w: %s", ErrValueTooLarge, k)
+}
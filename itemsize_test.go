@@ -0,0 +1,36 @@
+package minicache
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUpsertChecksMergedSize(t *testing.T) {
+	appendBytes := func(existing, v interface{}) interface{} {
+		return append(append([]byte(nil), existing.([]byte)...), v.([]byte)...)
+	}
+	for _, tc := range []struct {
+		name string
+		p    OversizePolicy
+		err  error
+	}{
+		{"Reject", OversizeReject, ErrValueTooLarge},
+		{"Skip", OversizeSkip, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewMiniCache(0, 0, WithSizer(SizerFunc(func(v interface{}) int64 { return int64(len(v.([]byte))) })), WithMaxItemSize(10, tc.p))
+			defer c.Close()
+			for i := 0; i < 2; i++ {
+				if err := c.Upsert("log", []byte("abcd"), 0, appendBytes); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := c.Upsert("log", []byte("abcd"), 0, appendBytes); !errors.Is(err, tc.err) {
+				t.Fatalf("Upsert() past the limit = %v, want %v", err, tc.err)
+			}
+			if v, _ := c.Get("log"); len(v.([]byte)) != 8 {
+				t.Fatalf(`Get("log") has %d bytes, want the 8 stored before the limit`, len(v.([]byte)))
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package minicache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+//遍历时复制的键值对
+type rangeEntry struct {
+	key   string
+	value interface{}
+}
+
+//在读锁下复制未过期的数据项
+func (minic *minicache) rangeEntries() []rangeEntry {
+	minic.rwmtx.RLock()
+	defer minic.rwmtx.RUnlock()
+	return minic.liveEntries(time.Now().UnixNano())
+}
+
+//复制now时未过期的数据项,调用方持有读锁
+func (minic *minicache) liveEntries(now int64) []rangeEntry {
+	entries := make([]rangeEntry, 0, minic.items.len())
+	minic.items.each(func(k string, item Item) bool {
+		if item.Expiration == 0 || now <= item.Expiration {
+			entries = append(entries, rangeEntry{k, item.Object})
+		}
+		return true
+	})
+	return entries
+}
+
+//遍历未过期的数据项,fn返回false时停止
+//遍历前在读锁下复制数据项,fn在锁外执行,可以修改缓存;修改不影响本次遍历看到的内容
+func (minic *minicache) Range(fn func(k string, v interface{}) bool) {
+	for _, e := range minic.rangeEntries() {
+		if !fn(e.key, e.value) {
+			return
+		}
+	}
+}
+
+//逐个分片遍历未过期的数据项,fn返回false时停止
+//只在复制当前分片时短暂持有该分片的读锁,其他分片的读写不受影响;遍历结果不是整个缓存在同一时刻的快照
+//改变分片数期间先遍历还没有开始迁移的旧分片,再遍历新分片并跳过来自这些旧分片的键,正在迁移的数据项只出现一次
+func (sc *ShardedCache) Range(fn func(k string, v interface{}) bool) {
+	old, cur := sc.tables.load()
+	var visited []bool //遍历过的旧分片,之后迁入新分片的键已经遍历过
+	if old != nil {
+		visited = make([]bool, len(old.shards))
+		for i := range old.shards {
+			entries, ok := old.rangeUnmoved(i)
+			if !ok {
+				continue
+			}
+			visited[i] = true
+			for _, e := range entries {
+				if !fn(e.key, e.value) {
+					return
+				}
+			}
+		}
+	}
+	for _, s := range cur.shards {
+		for _, e := range s.rangeEntries() {
+			if visited != nil && visited[sc.hasher(e.key)&old.mask] {
+				continue
+			}
+			if !fn(e.key, e.value) {
+				return
+			}
+		}
+	}
+}
+
+//在旧分片i开始迁移前复制它的数据项;已经开始迁移时等待迁移完成并返回false,它的数据项在新分片中遍历
+func (t *shardTable) rangeUnmoved(i int) ([]rangeEntry, bool) {
+	s := t.shards[i]
+	s.rwmtx.RLock()
+	//封住之后迁移才会取走数据项,持有读锁时还没有封住说明数据项都在旧分片中
+	if atomic.LoadInt64(&t.inflight[i].n) >= 0 {
+		defer s.rwmtx.RUnlock()
+		return s.liveEntries(time.Now().UnixNano()), true
+	}
+	s.rwmtx.RUnlock()
+	<-t.moved[i]
+	return nil, false
+}
@@ -0,0 +1,102 @@
+package minicache
+
+import (
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+//根据参数列表生成稳定的缓存键,用于多参数函数结果的缓存
+//参数按类型名加json编码规范化(map按键排序)后取sha256,无法编码的参数返回错误
+//json编码会忽略未导出的字段和标记为json:"-"的字段,只在这些字段上不同的参数会得到相同的键,
+//因此包含这类字段的参数同样返回错误;实现了json.Marshaler或encoding.TextMarshaler的类型按其编码结果计算
+func HashKey(prefix string, args ...interface{}) (string, error) {
+	h := sha256.New()
+	for i, arg := range args {
+		if err := checkLossless(reflect.ValueOf(arg), map[uintptr]bool{}); err != nil {
+			return "", fmt.Errorf("Error encoding argument %d: %v", i, err)
+		}
+		b, err := json.Marshal(arg)
+		if err != nil {
+			return "", fmt.Errorf("Error encoding argument %d: %v", i, err)
+		}
+		fmt.Fprintf(h, "%T:%d:", arg, len(b))
+		h.Write(b)
+	}
+	return prefix + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+//检查v的json编码是否包含全部字段,seen记录已访问的指针,避免循环引用
+func checkLossless(v reflect.Value, seen map[uintptr]bool) error {
+	if !v.IsValid() {
+		return nil
+	}
+	t := v.Type()
+	if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) ||
+		reflect.PointerTo(t).Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return checkLossless(v.Elem(), seen)
+	case reflect.Pointer:
+		if v.IsNil() || seen[v.Pointer()] {
+			return nil
+		}
+		seen[v.Pointer()] = true
+		return checkLossless(v.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.Tag.Get("json") == "-" {
+				return fmt.Errorf("field %s.%s is tagged json:\"-\" and would be ignored", t, f.Name)
+			}
+			if !f.IsExported() && !isEmbeddedStruct(f) {
+				return fmt.Errorf("field %s.%s is not exported and would be ignored", t, f.Name)
+			}
+			if !f.IsExported() && f.Type.Kind() == reflect.Pointer && v.Field(i).IsNil() {
+				continue
+			}
+			if err := checkLossless(v.Field(i), seen); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := checkLossless(iter.Value(), seen); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if k := t.Elem().Kind(); k <= reflect.Complex128 || k == reflect.String {
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := checkLossless(v.Index(i), seen); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//未导出的嵌入结构体,json编码时提升其导出字段
+func isEmbeddedStruct(f reflect.StructField) bool {
+	t := f.Type
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return f.Anonymous && t.Kind() == reflect.Struct
+}
@@ -0,0 +1,62 @@
+package minicache
+
+import (
+	"testing"
+	"time"
+)
+
+type hashPublic struct {
+	A int
+	B string `json:"b,omitempty"`
+}
+
+type hashPrivate struct {
+	A int
+	b string
+}
+
+type hashIgnored struct {
+	A int
+	B string `json:"-"`
+}
+
+type hashEmbedded struct {
+	hashPublic
+	When time.Time
+}
+
+func TestHashKey(t *testing.T) {
+	for name, tc := range map[string]struct {
+		args []interface{}
+		ok   bool
+	}{
+		"scalars":          {[]interface{}{1, "a", 2.5, true, nil}, true},
+		"map":              {[]interface{}{map[string]int{"b": 2, "a": 1}}, true},
+		"exported struct":  {[]interface{}{hashPublic{1, "x"}, &hashPublic{A: 2}}, true},
+		"embedded":         {[]interface{}{hashEmbedded{hashPublic{1, "x"}, time.Unix(1, 0)}}, true},
+		"unexported field": {[]interface{}{hashPrivate{1, "x"}}, false},
+		"ignored field":    {[]interface{}{hashIgnored{1, "x"}}, false},
+		"nested":           {[]interface{}{map[string]interface{}{"k": []interface{}{hashPrivate{}}}}, false},
+		"func":             {[]interface{}{func() {}}, false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := HashKey("p:", tc.args...)
+			if (err == nil) != tc.ok {
+				t.Fatalf("HashKey() error = %v, want ok = %v", err, tc.ok)
+			}
+		})
+	}
+}
+
+func TestHashKeyStable(t *testing.T) {
+	a, _ := HashKey("p:", map[string]int{"x": 1, "y": 2, "z": 3}, "s")
+	for i := 0; i < 20; i++ {
+		if b, _ := HashKey("p:", map[string]int{"z": 3, "y": 2, "x": 1}, "s"); b != a {
+			t.Fatalf("HashKey() = %s, then %s", a, b)
+		}
+	}
+	s, _ := HashKey("p:", "1")
+	if n, _ := HashKey("p:", 1); s == n {
+		t.Fatal("HashKey(\"1\") == HashKey(1)")
+	}
+}
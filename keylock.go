@@ -0,0 +1,47 @@
+package minicache
+
+import "sync"
+
+//按键加锁使用的锁数量
+const keyLockStripes = 64
+
+//按键分段的互斥锁,不同键可能共用一把锁
+type keyLocks [keyLockStripes]sync.Mutex
+
+func (l *keyLocks) of(k string) *sync.Mutex {
+	return &l[fnv64a(k)%keyLockStripes]
+}
+
+//持有k的键锁执行fn,同一个键上的WithLock依次执行,不阻塞其他键的读写
+//fn收到当前未过期的值,返回(新值, true)时写入新值并保留原有的过期时间(不存在时使用默认有效期),返回false时不做修改
+//fn执行期间不持有缓存锁,可以读写其他键,但不能对可能共用键锁的键再调用WithLock;不经过WithLock的写操作不受键锁约束
+func (minic *minicache) WithLock(k string, fn func(v interface{}, found bool) (interface{}, bool)) error {
+	mtx := minic.keyLocks.of(k)
+	mtx.Lock()
+	defer mtx.Unlock()
+	minic.rwmtx.RLock()
+	old, found := minic.items.get(k)
+	minic.rwmtx.RUnlock()
+	if found && old.IsExpired() {
+		found = false
+	}
+	v, ok := fn(old.Object, found)
+	if !ok {
+		return nil
+	}
+	if closed, err := minic.closed(); closed {
+		return err
+	}
+	if over, err := minic.oversize(k, v); over {
+		return err
+	}
+	minic.rwmtx.Lock()
+	defer minic.writeUnlock()
+	item := minic.newItem(k, v, DefaultExpiration, SourceSet, minic.sliding)
+	if current, exists := minic.items.get(k); found && exists && !current.IsExpired() {
+		item.Expiration = current.Expiration
+		item.Sliding = current.Sliding
+		item.priority = current.priority
+	}
+	return minic.store(k, item)
+}
@@ -0,0 +1,68 @@
+package minicache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithLockSerializesUpdates(t *testing.T) {
+	for name, c := range map[string]Cache{
+		"Minicache":    NewMiniCache(0, 0),
+		"ShardedCache": NewShardedCache(0, 0, WithShards(4)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer c.Close()
+			var wg sync.WaitGroup
+			for g := 0; g < 8; g++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < 500; i++ {
+						err := c.WithLock("n", func(v interface{}, found bool) (interface{}, bool) {
+							n, _ := v.(int)
+							return n + 1, true
+						})
+						if err != nil {
+							t.Error(err)
+							return
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			if v, _ := c.Get("n"); v != 4000 {
+				t.Fatalf("Get(n) = %v after 4000 concurrent increments", v)
+			}
+		})
+	}
+}
+
+func TestWithLockKeepsExpiration(t *testing.T) {
+	c := NewMiniCache(0, 0)
+	defer c.Close()
+	c.Set("k", 1, time.Hour)
+	before, _ := c.Inspect("k")
+	c.WithLock("k", func(v interface{}, found bool) (interface{}, bool) {
+		return v.(int) + 1, true
+	})
+	after, _ := c.Inspect("k")
+	if after.Object != 2 || after.Expiration != before.Expiration {
+		t.Fatalf("WithLock stored %v expiring at %d, want 2 expiring at %d", after.Object, after.Expiration, before.Expiration)
+	}
+	c.WithLock("k", func(v interface{}, found bool) (interface{}, bool) {
+		return 100, false
+	})
+	if v, _ := c.Get("k"); v != 2 {
+		t.Fatalf("WithLock returning false changed the value to %v", v)
+	}
+	c.WithLock("missing", func(v interface{}, found bool) (interface{}, bool) {
+		if found || v != nil {
+			t.Errorf("fn(%v, %v) for a missing key", v, found)
+		}
+		return false, false
+	})
+	if _, found := c.Get("missing"); found {
+		t.Fatal("WithLock returning false created the key")
+	}
+}
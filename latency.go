@@ -0,0 +1,147 @@
+package minicache
+
+import (
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+//延迟统计的操作类型
+type LatencyOp int
+
+const (
+	LatencyGet LatencyOp = iota
+	LatencySet
+	LatencyDelete
+	LatencyLoad
+	latencyOps
+)
+
+//每个2的幂区间再细分为8个子区间,相对误差不超过12.5%
+const (
+	latencySubBits    = 3
+	latencySubBuckets = 1 << latencySubBits
+	latencyBuckets    = latencySubBuckets * 64
+)
+
+//对数分桶的延迟直方图,无锁写入
+type latencyHistogram struct {
+	buckets [latencyBuckets]uint64
+	sum     uint64
+	max     uint64
+}
+
+func latencyBucket(v uint64) int {
+	if v < latencySubBuckets {
+		return int(v)
+	}
+	e := bits.Len64(v) - latencySubBits - 1
+	m := v >> uint(e)
+	return latencySubBuckets + e*latencySubBuckets + int(m-latencySubBuckets)
+}
+
+//分桶上界
+func latencyBucketUpper(i int) uint64 {
+	if i < latencySubBuckets {
+		return uint64(i)
+	}
+	e := (i - latencySubBuckets) / latencySubBuckets
+	m := uint64(i-latencySubBuckets)%latencySubBuckets + latencySubBuckets
+	return (m+1)<<uint(e) - 1
+}
+
+func (h *latencyHistogram) observe(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	v := uint64(d)
+	atomic.AddUint64(&h.buckets[latencyBucket(v)], 1)
+	atomic.AddUint64(&h.sum, v)
+	for {
+		max := atomic.LoadUint64(&h.max)
+		if v <= max || atomic.CompareAndSwapUint64(&h.max, max, v) {
+			return
+		}
+	}
+}
+
+//延迟直方图快照
+type LatencySnapshot struct {
+	Count   uint64
+	Sum     time.Duration
+	Max     time.Duration
+	buckets [latencyBuckets]uint64
+}
+
+//返回分位数对应的延迟上界,q取值[0,1]
+func (s LatencySnapshot) Quantile(q float64) time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	rank := uint64(q*float64(s.Count) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+	var seen uint64
+	for i, n := range s.buckets {
+		seen += n
+		if seen >= rank {
+			if d := time.Duration(latencyBucketUpper(i)); d < s.Max {
+				return d
+			}
+			return s.Max
+		}
+	}
+	return s.Max
+}
+
+//累加另一个快照,用于汇总多个分片的统计
+func (s *LatencySnapshot) add(o LatencySnapshot) {
+	for i, n := range o.buckets {
+		s.buckets[i] += n
+	}
+	s.Count += o.Count
+	s.Sum += o.Sum
+	s.Max = max(s.Max, o.Max)
+}
+
+//平均延迟
+func (s LatencySnapshot) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / time.Duration(s.Count)
+}
+
+//各操作的延迟统计
+type latencyTracker struct {
+	ops [latencyOps]latencyHistogram
+}
+
+//记录从start开始的操作耗时
+func (t *latencyTracker) since(op LatencyOp, start time.Time) {
+	t.ops[op].observe(time.Since(start))
+}
+
+//开启操作延迟统计
+func WithLatencyTracking() Option {
+	return func(minic *Minicache) {
+		minic.latency = &latencyTracker{}
+	}
+}
+
+//返回操作的延迟直方图快照,未开启统计时返回零值
+func (minic *minicache) Latency(op LatencyOp) LatencySnapshot {
+	var s LatencySnapshot
+	if minic.latency == nil || op < 0 || op >= latencyOps {
+		return s
+	}
+	h := &minic.latency.ops[op]
+	for i := range h.buckets {
+		s.buckets[i] = atomic.LoadUint64(&h.buckets[i])
+		s.Count += s.buckets[i]
+	}
+	s.Sum = time.Duration(atomic.LoadUint64(&h.sum))
+	s.Max = time.Duration(atomic.LoadUint64(&h.max))
+	return s
+}
@@ -0,0 +1,112 @@
+package minicache
+
+import "container/heap"
+
+//每经过maxEntries的若干倍次访问,所有访问频率减半
+const lfuDecayFactor = 10
+
+type lfuEntry struct {
+	key   string
+	freq  uint32
+	tick  uint64 //最近访问序号,频率相同时先淘汰较久未访问的
+	index int
+}
+
+type lfuHeap []*lfuEntry
+
+func (h lfuHeap) Len() int { return len(h) }
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].tick < h[j].tick
+}
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *lfuHeap) Push(x interface{}) {
+	e := x.(*lfuEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+func (h *lfuHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
+
+//最不经常使用,按频率建最小堆,定期衰减避免过去的热点永远不被淘汰
+type lfu struct {
+	entries    map[string]*lfuEntry
+	heap       lfuHeap
+	tick       uint64
+	decayEvery int
+	ops        int
+}
+
+func newLFU(decayEvery int) *lfu {
+	return &lfu{entries: map[string]*lfuEntry{}, decayEvery: decayEvery}
+}
+
+func (p *lfu) add(k string) {
+	if _, ok := p.entries[k]; ok {
+		p.access(k)
+		return
+	}
+	p.tick++
+	e := &lfuEntry{key: k, freq: 1, tick: p.tick}
+	p.entries[k] = e
+	heap.Push(&p.heap, e)
+	p.count()
+}
+
+func (p *lfu) access(k string) {
+	e, ok := p.entries[k]
+	if !ok {
+		return
+	}
+	p.tick++
+	e.tick = p.tick
+	if e.freq < ^uint32(0) {
+		e.freq++
+	}
+	heap.Fix(&p.heap, e.index)
+	p.count()
+}
+
+//计数并在达到衰减周期时将所有频率减半,减半后相对顺序基本不变,重建堆即可
+func (p *lfu) count() {
+	if p.ops++; p.decayEvery <= 0 || p.ops < p.decayEvery {
+		return
+	}
+	p.ops = 0
+	for _, e := range p.heap {
+		e.freq >>= 1
+	}
+	heap.Init(&p.heap)
+}
+
+func (p *lfu) remove(k string) {
+	e, ok := p.entries[k]
+	if !ok {
+		return
+	}
+	heap.Remove(&p.heap, e.index)
+	delete(p.entries, k)
+}
+
+func (p *lfu) victim() (string, bool) {
+	if len(p.heap) == 0 {
+		return "", false
+	}
+	return p.heap[0].key, true
+}
+
+func (p *lfu) reset() {
+	p.entries = map[string]*lfuEntry{}
+	p.heap = nil
+	p.ops = 0
+}
@@ -0,0 +1,136 @@
+package minicache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+//缓存生命周期状态
+const (
+	stateOpen int32 = iota
+	stateClosing
+	stateClosed
+)
+
+//关闭后写操作的处理方式
+type ClosePolicy int
+
+const (
+	CloseReject ClosePolicy = iota //立即返回ErrCacheClosed
+	CloseBlock                     //关闭过程中阻塞到关闭完成,然后返回ErrCacheClosed
+	CloseIgnore                    //静默忽略,不返回错误
+)
+
+//设置Close之后写操作的处理方式,默认CloseReject
+func WithClosePolicy(p ClosePolicy) Option {
+	return func(minic *Minicache) {
+		minic.closePolicy = p
+	}
+}
+
+//判断缓存是否已关闭,已关闭时按关闭策略返回写操作应返回的错误
+//CloseIgnore策略下返回(true, nil),调用方应直接返回
+func (minic *minicache) closed() (bool, error) {
+	switch atomic.LoadInt32(&minic.state) {
+	case stateOpen:
+		if minic.Frozen() {
+			return true, ErrReadOnly
+		}
+		return false, nil
+	case stateClosing:
+		if minic.closePolicy == CloseBlock {
+			<-minic.done
+		}
+	}
+	if minic.closePolicy == CloseIgnore {
+		return true, nil
+	}
+	return true, ErrCacheClosed
+}
+
+//注册关闭时执行的函数,Close停止gc后按注册的相反顺序调用,此时缓存仍可读取
+//Close之后注册的函数不会被调用
+func (minic *minicache) OnClose(fn func() error) {
+	minic.closeHooks.add(fn)
+}
+
+//OnClose注册的关闭函数
+type closeHooks struct {
+	mtx   sync.Mutex
+	hooks []func() error
+}
+
+func (h *closeHooks) add(fn func() error) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.hooks = append(h.hooks, fn)
+}
+
+//按注册的相反顺序执行关闭函数,返回所有错误
+func (h *closeHooks) run() error {
+	h.mtx.Lock()
+	hooks := h.hooks
+	h.hooks = nil
+	h.mtx.Unlock()
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i](); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+//关闭缓存,停止gc并执行OnClose注册的函数,可重复调用,只有第一次调用返回关闭函数的错误
+//关闭后写操作按关闭策略处理,读操作不受影响;已进入写锁的写操作会在关闭前完成
+func (minic *minicache) Close() error {
+	if !atomic.CompareAndSwapInt32(&minic.state, stateOpen, stateClosing) {
+		<-minic.done
+		return nil
+	}
+	//等待已通过状态检查的异步写入放入缓冲区,之后的写入按关闭策略处理,再等待缓冲区写完
+	minic.writesMtx.Lock()
+	minic.writesMtx.Unlock()
+	minic.Wait()
+	//等待已持有写锁的写操作完成
+	minic.rwmtx.Lock()
+	minic.rwmtx.Unlock()
+	minic.Stopgc()
+	err := minic.closeHooks.run()
+	if minic.aof != nil {
+		err = errors.Join(err, minic.aof.close())
+	}
+	atomic.StoreInt32(&minic.state, stateClosed)
+	close(minic.done)
+	return err
+}
+
+//设置持久化文件,Shutdown关闭缓存后将数据保存到该文件
+func WithPersistence(fileName string) Option {
+	return func(minic *Minicache) {
+		minic.persistFile = fileName
+	}
+}
+
+//优雅关闭:关闭缓存并执行OnClose函数,等待正在进行的gc(包括过期回调)和异步写入结束,
+//配置了WithPersistence时最后保存一次快照
+//ctx到期时返回ctx.Err(),剩余的关闭步骤在后台继续完成
+func (minic *minicache) Shutdown(ctx context.Context) error {
+	result := make(chan error, 1)
+	go func() {
+		err := minic.Close()
+		minic.background.Wait()
+		if minic.persistFile != "" {
+			err = errors.Join(err, minic.SaveToFile(minic.persistFile))
+		}
+		result <- err
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,49 @@
+package minicache
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+//加载函数,返回键对应的值和缓存有效期
+type Loader func(k string) (interface{}, time.Duration, error)
+
+//为键前缀注册加载函数,空前缀作为兜底加载函数,同一键匹配多个前缀时以最长前缀为准
+func (minic *minicache) RegisterLoader(prefix string, loader Loader) {
+	minic.rwmtx.Lock()
+	defer minic.rwmtx.Unlock()
+	if minic.loaders == nil {
+		minic.loaders = map[string]Loader{}
+	}
+	minic.loaders[prefix] = loader
+}
+
+//查找键对应的加载函数
+func (minic *minicache) loaderFor(k string) (Loader, bool) {
+	minic.rwmtx.RLock()
+	defer minic.rwmtx.RUnlock()
+	var loader Loader
+	matched := -1
+	for prefix, l := range minic.loaders {
+		if len(prefix) > matched && strings.HasPrefix(k, prefix) {
+			matched = len(prefix)
+			loader = l
+		}
+	}
+	return loader, matched >= 0
+}
+
+//获取缓存,未命中时使用按前缀注册的加载函数加载,没有匹配的加载函数时返回ErrNoLoader
+func (minic *minicache) GetOrLoad(k string) (interface{}, error) {
+	if v, found := minic.Get(k); found {
+		return v, nil
+	}
+	loader, ok := minic.loaderFor(k)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoLoader, k)
+	}
+	return minic.load(k, func() (interface{}, time.Duration, error) {
+		return loader(k)
+	})
+}
@@ -0,0 +1,111 @@
+package minicache
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+//加载函数panic时等待者收到的错误
+var errMemoPanic = errors.New("memoized function panicked")
+
+//进行中的加载调用
+type memoCall struct {
+	wg  sync.WaitGroup
+	val interface{}
+	err error
+}
+
+//缓存的加载错误
+type memoErr struct {
+	err        error
+	expiration int64
+}
+
+//Memoize的并发去重与错误缓存状态
+type memoizer struct {
+	mtx   sync.Mutex
+	calls map[string]*memoCall
+	errs  map[string]memoErr
+}
+
+//缓存fn返回的错误d时长,期间Memoize直接返回该错误,默认不缓存错误
+func WithErrorCaching(d time.Duration) Option {
+	return func(minic *Minicache) {
+		minic.errorCaching = d
+	}
+}
+
+//返回k对应的缓存值,未命中时调用fn加载并以有效期d缓存结果
+//同一键的并发调用只执行一次fn,其余调用等待并共享结果
+func (minic *minicache) Memoize(k string, d time.Duration, fn func() (interface{}, error)) (interface{}, error) {
+	return minic.load(k, func() (interface{}, time.Duration, error) {
+		v, err := fn()
+		return v, d, err
+	})
+}
+
+//未命中时调用fn加载,并以fn返回的有效期缓存结果,同一键的并发加载只执行一次
+func (minic *minicache) load(k string, fn func() (interface{}, time.Duration, error)) (interface{}, error) {
+	if v, found := minic.Get(k); found {
+		return v, nil
+	}
+	m := &minic.memo
+	m.mtx.Lock()
+	if e, ok := m.errs[k]; ok {
+		if time.Now().UnixNano() <= e.expiration {
+			m.mtx.Unlock()
+			return nil, e.err
+		}
+		delete(m.errs, k)
+	}
+	if c, ok := m.calls[k]; ok {
+		m.mtx.Unlock()
+		c.wg.Wait()
+		return c.val, c.err
+	}
+	c := &memoCall{}
+	c.wg.Add(1)
+	if m.calls == nil {
+		m.calls = map[string]*memoCall{}
+	}
+	m.calls[k] = c
+	m.mtx.Unlock()
+
+	defer minic.finishMemo(k, c)
+	//等待者进入前可能已有其他调用完成加载
+	if v, found := minic.Get(k); found {
+		c.val = v
+		return c.val, nil
+	}
+	start := time.Now()
+	c.err = errMemoPanic
+	var d time.Duration
+	c.val, d, c.err = fn()
+	if minic.latency != nil {
+		minic.latency.since(LatencyLoad, start)
+	}
+	if closed, _ := minic.closed(); c.err == nil && !closed {
+		if over, _ := minic.oversize(k, c.val); !over {
+			minic.rwmtx.Lock()
+			minic.setFrom(k, c.val, d, SourceLoader)
+			minic.unlock()
+		}
+	}
+	return c.val, c.err
+}
+
+//结束加载调用并唤醒等待者,fn发生panic时等待者得到errMemoPanic
+func (minic *minicache) finishMemo(k string, c *memoCall) {
+	m := &minic.memo
+	m.mtx.Lock()
+	delete(m.calls, k)
+	if c.err != nil && c.err != errMemoPanic && minic.errorCaching > 0 {
+		if m.errs == nil {
+			m.errs = map[string]memoErr{}
+		}
+		m.errs[k] = memoErr{err: c.err, expiration: time.Now().Add(minic.errorCaching).UnixNano()}
+	}
+	m.mtx.Unlock()
+	c.wg.Done()
+}
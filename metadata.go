@@ -0,0 +1,92 @@
+package minicache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+//数据项的访问元数据,读操作在读锁下原子更新
+type itemMeta struct {
+	created  int64
+	accessed int64
+	hits     uint64
+}
+
+//为每个数据项维护写入时间、最近访问时间和访问次数,Set会重置这些信息
+//元数据不写入快照,Load时以加载时间作为写入时间
+func WithItemMetadata() Option {
+	return func(minic *Minicache) {
+		minic.itemMetadata = true
+	}
+}
+
+//写入时间,未开启元数据时返回零值
+func (item Item) CreatedAt() time.Time {
+	if item.meta == nil {
+		return time.Time{}
+	}
+	return time.Unix(0, item.meta.created)
+}
+
+//最近一次被Get命中的时间,未开启元数据或从未被访问时返回零值
+func (item Item) LastAccessedAt() time.Time {
+	if item.meta == nil {
+		return time.Time{}
+	}
+	if t := atomic.LoadInt64(&item.meta.accessed); t != 0 {
+		return time.Unix(0, t)
+	}
+	return time.Time{}
+}
+
+//被Get命中的次数,未开启元数据时返回0
+func (item Item) AccessCount() uint64 {
+	if item.meta == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&item.meta.hits)
+}
+
+//记录一次访问
+func (m *itemMeta) access(now int64) {
+	atomic.StoreInt64(&m.accessed, now)
+	atomic.AddUint64(&m.hits, 1)
+}
+
+//数据项及其元数据的快照
+type ItemMeta struct {
+	Value          interface{}
+	Expiration     time.Time //过期时间,永不过期时为零值
+	CreatedAt      time.Time
+	LastAccessedAt time.Time
+	AccessCount    uint64
+	Source         ItemSource
+	Sliding        time.Duration
+}
+
+//在一次加锁中读取未过期数据项的值和元数据,不计入访问次数,也不顺延滑动有效期
+//未开启WithItemMetadata时时间和次数字段为零值
+func (minic *minicache) GetWithMeta(k string) (ItemMeta, bool) {
+	minic.rwmtx.RLock()
+	defer minic.rwmtx.RUnlock()
+	item, found := minic.items.get(k)
+	expired := found && item.IsExpired()
+	for _, sim := range minic.sims {
+		sim.get(k, found && !expired, expired)
+	}
+	if !found || expired {
+		return ItemMeta{}, false
+	}
+	meta := ItemMeta{
+		Value:          item.Object,
+		CreatedAt:      item.CreatedAt(),
+		LastAccessedAt: item.LastAccessedAt(),
+		AccessCount:    item.AccessCount(),
+		Source:         item.Source,
+		Sliding:        item.Sliding,
+	}
+	if item.Expiration > 0 {
+		meta.Expiration = time.Unix(0, item.Expiration)
+	}
+	return meta, true
+}
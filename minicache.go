@@ -4,27 +4,223 @@ import (
 	"encoding/gob"
 	"fmt"
 	"io"
+	"math/rand"
 	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const (
-	NoExpiration      time.Duration = -1
-	defaultExpiration time.Duration = 0
+	NoExpiration      time.Duration = -1 //永不过期,忽略缓存的默认有效期
+	DefaultExpiration time.Duration = 0  //使用缓存的默认有效期
 )
 
 type Item struct {
 	Object     interface{}
 	Expiration int64
+	Source     ItemSource
+	Sliding    time.Duration //滑动有效期,大于0时每次命中都将过期时间顺延该时长
+	read       bool          //写入后是否被读取过,仅在开启未读统计时维护
+	meta       *itemMeta     //访问元数据,仅在开启WithItemMetadata时维护
+	cost       int64         //淘汰时计入总开销的权重
+	priority   int           //淘汰优先级,越低越先淘汰
+	shared     *dedupEntry   //开启WithValueDedup时Object在去重表中的登记
 }
 
+//数据项来源
+type ItemSource uint8
+
+const (
+	SourceSet      ItemSource = iota //显式写入
+	SourceLoader                     //Memoize等加载函数
+	SourceSnapshot                   //从快照加载
+	SourceWarm                       //Warm预热
+)
+
+func (src ItemSource) String() string {
+	switch src {
+	case SourceSet:
+		return "set"
+	case SourceLoader:
+		return "loader"
+	case SourceSnapshot:
+		return "snapshot"
+	case SourceWarm:
+		return "warm"
+	}
+	return fmt.Sprintf("source(%d)", uint8(src))
+}
+
+//缓存句柄,后台goroutine只引用内部的minicache
+//句柄不再被引用时由终结器关闭缓存,未调用Close的缓存不会泄漏gc goroutine
 type Minicache struct {
-	defaultExpiration time.Duration
-	items             map[string]Item
+	*minicache
+}
+
+type minicache struct {
+	defaultExpiration int64 //原子访问
+	expiredRetention  time.Duration
+	items             itemStore
 	rwmtx             sync.RWMutex
 	gcInterval        time.Duration
 	stopGc            chan bool
+	events            *eventHub
+	eventTags         func(k string, v interface{}) []string
+	overrides         map[string]ttlOverride
+	latency           *latencyTracker
+	memo              memoizer
+	errorCaching      time.Duration
+	scopes            map[string]*scope
+	nsMetrics         *nsMetrics
+	loadBatchSize     int
+	sliding           bool
+	ttlJitter         float64
+	maxTTL            time.Duration
+	expiryNotice      time.Duration
+	expiries          expiryHeap
+	notices           expiryHeap
+	gcSamples         int
+	gcSampleRatio     float64
+	gcBatchSize       int
+	loaders           map[string]Loader
+	cardinality       *cardinality
+	gcMinInterval     time.Duration
+	gcMaxInterval     time.Duration
+	deadEntries       *deadEntries
+	expirationBucket  time.Duration
+	onExpired         func(k string, v interface{})
+	dedup             *dedupTable
+	expireOnWrite     int
+	gcStats           gcStatsRecorder
+	itemMetadata      bool
+	prefixTTLs        map[string]time.Duration
+	evictor           atomic.Pointer[evictor] //Resize可能在运行时创建,Get在锁外读取
+	maxEntries        int
+	evictionPolicy    EvictionPolicy
+	admission         bool
+	maxCost           int64
+	totalCost         int64
+	sizer             Sizer
+	maxItemSize       int64
+	oversizePolicy    OversizePolicy
+	pins              map[string]struct{}
+	onEvicted         func(k string, v interface{}, reason EvictionReason)
+	evictedPending    []evictedEntry
+	expiredPending    []expiredEntry //reserve删除的过期数据项,释放写锁后执行OnExpired
+	removals          [reasonCount]uint64
+	admissionRejects  uint64
+	hotKeys           *hotKeys
+	doorkeeperOn      bool
+	doorkeeperWindow  time.Duration
+	doorkeeper        *doorkeeper
+	doorkeeperRejects uint64
+	sims              []*policySim
+	keyLocks          keyLocks
+	writes            chan writeOp
+	writesMtx         sync.RWMutex //放入缓冲区时持有读锁,Close持有写锁确认之后不再有写入放入
+	closeHooks        closeHooks
+	background        sync.WaitGroup //后台gc和异步写入goroutine
+	persistFile       string
+	frozen            int32
+	initialCapacity   int
+	autoSaveInterval  time.Duration
+	onAutoSaveError   func(error)
+	autoSaveErr       atomic.Pointer[error] //最近一次自动保存或检查的结果
+	aofFile           string
+	aofSyncInterval   time.Duration
+	onAOFError        func(error)
+	aofRewriteMin     int64
+	aof               *aofLog
+	aofPaused         bool //回放日志时不再记录
+	snapCompression   SnapshotCompression
+	snapLevel         int
+	snapKeys          SnapshotKeyProvider
+	readMap           *sync.Map
+	state             int32
+	closePolicy       ClosePolicy
+	done              chan struct{}
+	stopOnce          sync.Once
+}
+
+//缓存配置项
+type Option func(*Minicache)
+
+//过期数据项保留时长,保留期内Get不可见,但可通过GetStale读取、通过Revalidate续期,保留期结束后由gc删除
+func WithExpiredRetention(d time.Duration) Option {
+	return func(minic *Minicache) {
+		minic.expiredRetention = d
+	}
+}
+
+//Load合并数据项时每批加锁处理的数量,默认1000
+func WithLoadBatchSize(n int) Option {
+	return func(minic *Minicache) {
+		minic.loadBatchSize = n
+	}
+}
+
+//所有带有效期的数据项都按滑动过期处理,每次Get命中顺延其有效期
+func WithSlidingExpiration() Option {
+	return func(minic *Minicache) {
+		minic.sliding = true
+	}
+}
+
+//写入时把有效期随机调整到[d*(1-fraction), d*(1+fraction)]范围内,fraction取值(0,1)
+func WithTTLJitter(fraction float64) Option {
+	return func(minic *Minicache) {
+		minic.ttlJitter = fraction
+	}
+}
+
+//有效期上限,超过上限的有效期(包括NoExpiration)被截断为d
+func WithMaxTTL(d time.Duration) Option {
+	return func(minic *Minicache) {
+		minic.maxTTL = d
+	}
+}
+
+//在数据项过期前lead时长发布EventExpiring事件,由gc循环从通知堆中取出,精度取决于gcInterval
+func WithExpiryNotice(lead time.Duration) Option {
+	return func(minic *Minicache) {
+		minic.expiryNotice = lead
+	}
+}
+
+//使用抽样方式清理过期数据项,每轮随机检查samples个数据项,
+//过期比例超过ratio时继续下一轮,适合数据量很大的缓存,不再维护过期堆
+func WithSampledExpiration(samples int, ratio float64) Option {
+	return func(minic *Minicache) {
+		minic.gcSamples = samples
+		minic.gcSampleRatio = ratio
+	}
+}
+
+//gc每批处理的到期数据项数量,批次之间释放锁,默认1000
+func WithGCBatchSize(n int) Option {
+	return func(minic *Minicache) {
+		minic.gcBatchSize = n
+	}
+}
+
+//根据每次gc清理出的过期比例自动调整gc间隔,过期数据多时缩短,少时延长,范围为[min, max]
+func WithAdaptiveGC(min, max time.Duration) Option {
+	return func(minic *Minicache) {
+		minic.gcMinInterval = min
+		minic.gcMaxInterval = max
+	}
+}
+
+//把过期时间向上取整到d的整数倍,过期时间相同的数据项在过期堆中合并为一个节点,
+//以少量的过期延迟换取大量相近有效期数据项下更小的堆
+func WithExpirationBucket(d time.Duration) Option {
+	return func(minic *Minicache) {
+		minic.expirationBucket = d
+	}
 }
 
 func (item Item) IsExpired() bool {
@@ -34,179 +230,1054 @@ func (item Item) IsExpired() bool {
 	return time.Now().UnixNano() > item.Expiration
 }
 
-//循环gc
-func (minic *Minicache) gcLoop() {
-	ticker := time.NewTicker(minic.gcInterval) //初始化定时器
+//循环gc,开启自适应间隔时根据每次清理的过期比例调整下一次间隔
+func (minic *minicache) gcLoop() {
+	defer minic.background.Done()
+	interval := minic.gcInterval
+	timer := time.NewTimer(interval) //初始化定时器
 	for {
 		select {
-		case <-ticker.C:
-			minic.DeleteExpired()
+		case <-timer.C:
+			stats := minic.deleteExpired(nil)
+			if minic.gcMaxInterval > 0 {
+				interval = minic.adaptInterval(interval, stats.Deleted, stats.Items)
+			}
+			timer.Reset(interval)
 		case <-minic.stopGc:
-			ticker.Stop()
+			timer.Stop()
 			return
 		}
 	}
 }
 
-//过期缓存删除
-func (minic *Minicache) DeleteExpired() {
-	now := time.Now().UnixNano()
+//过期比例高于该值时缩短gc间隔,低于该值的十分之一时延长gc间隔
+const adaptiveExpiredRatio = 0.1
+
+//计算下一次gc间隔,结果限制在[gcMinInterval, gcMaxInterval]内
+func (minic *minicache) adaptInterval(interval time.Duration, removed, total int) time.Duration {
+	var ratio float64
+	if total > 0 {
+		ratio = float64(removed) / float64(total)
+	}
+	switch {
+	case ratio > adaptiveExpiredRatio:
+		interval /= 2
+	case ratio < adaptiveExpiredRatio/10:
+		interval *= 2
+	}
+	lower := minic.gcMinInterval
+	if lower <= 0 {
+		lower = time.Millisecond
+	}
+	if interval < lower {
+		interval = lower
+	}
+	if interval > minic.gcMaxInterval {
+		interval = minic.gcMaxInterval
+	}
+	return interval
+}
+
+//过期缓存删除,配置了保留时长的数据项在保留期结束后删除,返回删除的数量
+//只处理过期堆中已到期的节点,不扫描全部数据项,每处理一批释放一次锁
+func (minic *minicache) DeleteExpired() int {
+	return minic.deleteExpired(nil).Deleted
+}
+
+//过期缓存删除,并对每个被删除的数据项调用fn,fn在锁外调用
+func (minic *minicache) DeleteExpiredFunc(fn func(k string, v interface{})) int {
+	return minic.deleteExpired(fn).Deleted
+}
+
+//被gc删除的数据项
+type expiredEntry struct {
+	key    string
+	object interface{}
+}
+
+//注册数据项过期回调,由gc和访问时的惰性删除在锁外调用,再次注册会替换之前的回调
+func (minic *minicache) OnExpired(fn func(k string, v interface{})) {
 	minic.rwmtx.Lock()
 	defer minic.rwmtx.Unlock()
-	for k, v := range minic.items {
-		if v.Expiration > 0 && now > v.Expiration {
-			minic.delete(k)
+	minic.onExpired = fn
+}
+
+//过期缓存删除,返回本次清理的统计
+//fn和注册的过期回调都会对每个被删除的数据项调用
+func (minic *minicache) deleteExpired(fn func(k string, v interface{})) GCStats {
+	start := time.Now()
+	if minic.Frozen() {
+		return GCStats{Start: start}
+	}
+	minic.rwmtx.RLock()
+	stats := GCStats{Start: start, Items: minic.items.len()}
+	onExpired := minic.onExpired
+	minic.rwmtx.RUnlock()
+	collect := fn != nil || onExpired != nil
+	now := start.UnixNano()
+	if minic.expiryNotice > 0 {
+		for minic.noticeExpiring(now) {
+		}
+	}
+	deadline := now - int64(minic.expiredRetention) - 1
+	for round := 0; ; round++ {
+		var b sweepBatch
+		if minic.gcSamples > 0 {
+			b = minic.deleteSampledRound(deadline, collect)
+			b.more = b.more && round+1 < maxSampleRounds
+		} else {
+			b = minic.deleteExpiredBatch(deadline, collect)
+		}
+		stats.Batches++
+		stats.Scanned += b.scanned
+		stats.Deleted += b.deleted
+		stats.LockHeld += b.held
+		if b.held > stats.MaxLockHeld {
+			stats.MaxLockHeld = b.held
+		}
+		for _, e := range b.expired {
+			if fn != nil {
+				fn(e.key, e.object)
+			}
+			if onExpired != nil {
+				onExpired(e.key, e.object)
+			}
+		}
+		if !b.more {
+			stats.Duration = time.Since(start)
+			minic.recordGC(stats)
+			return stats
 		}
 	}
 }
 
-//删除
-func (minic *Minicache) delete(k string) {
-	delete(minic.items, k)
+//一批删除的结果
+type sweepBatch struct {
+	expired []expiredEntry //collect为true时返回的被删除数据项
+	scanned int            //检查过的键数量
+	deleted int            //删除的数量
+	held    time.Duration  //持有写锁的时长
+	more    bool           //是否需要继续下一批
 }
 
-//删除操作
-func (minic *Minicache) Delete(k string) {
+//删除一批到期数据项
+func (minic *minicache) deleteExpiredBatch(deadline int64, collect bool) sweepBatch {
+	minic.rwmtx.Lock()
+	locked := time.Now()
+	b := minic.deleteDue(deadline, minic.gcBatchSize, collect)
+	b.held = time.Since(locked)
+	minic.unlock()
+	return b
+}
+
+//从过期堆中取出至多limit个到期键并删除,无锁
+func (minic *minicache) deleteDue(deadline int64, limit int, collect bool) (b sweepBatch) {
+	for b.scanned < limit {
+		node, ok := minic.expiries.popDue(deadline)
+		if !ok {
+			return b
+		}
+		b.scanned += len(node.keys)
+		for _, k := range node.keys {
+			if v, found := minic.items.get(k); found && v.Expiration == node.expiration {
+				minic.delete(k, EventExpire)
+				b.deleted++
+				if collect {
+					b.expired = append(b.expired, expiredEntry{key: k, object: v.Object})
+				}
+			}
+		}
+		minic.expiries.release(node)
+	}
+	b.more = true
+	return b
+}
+
+//抽样删除的最大轮数,限制单次gc的耗时
+const maxSampleRounds = 16
+
+//执行一轮抽样删除:随机抽样gcSamples个数据项删除其中已过期的,过期比例超过阈值时需要继续下一轮
+//map遍历的起点是随机的,因此连续取前N个数据项即近似随机抽样
+func (minic *minicache) deleteSampledRound(deadline int64, collect bool) sweepBatch {
+	minic.rwmtx.Lock()
+	locked := time.Now()
+	b := minic.deleteSampled(deadline, minic.gcSamples, collect)
+	b.held = time.Since(locked)
+	minic.unlock()
+	return b
+}
+
+//随机抽样samples个数据项并删除其中已过期的,无锁
+func (minic *minicache) deleteSampled(deadline int64, samples int, collect bool) (b sweepBatch) {
+	minic.items.each(func(k string, v Item) bool {
+		if b.scanned == samples {
+			return false
+		}
+		b.scanned++
+		if v.Expiration > 0 && v.Expiration <= deadline {
+			minic.delete(k, EventExpire)
+			b.deleted++
+			if collect {
+				b.expired = append(b.expired, expiredEntry{key: k, object: v.Object})
+			}
+		}
+		return true
+	})
+	b.more = b.scanned > 0 && float64(b.deleted)/float64(b.scanned) > minic.gcSampleRatio
+	return b
+}
+
+//为一批进入提前通知窗口的数据项发布EventExpiring,返回是否还有未处理的节点
+func (minic *minicache) noticeExpiring(now int64) bool {
 	minic.rwmtx.Lock()
-	minic.delete(k)
 	defer minic.rwmtx.Unlock()
+	for processed := 0; processed < minic.gcBatchSize; {
+		node, ok := minic.notices.popDue(now)
+		if !ok {
+			return false
+		}
+		processed += len(node.keys)
+		e := node.expiration + int64(minic.expiryNotice)
+		for _, k := range node.keys {
+			if v, found := minic.items.get(k); found && v.Expiration == e && now <= e {
+				minic.publish(EventExpiring, k, v)
+			}
+		}
+		minic.notices.release(node)
+	}
+	return true
+}
+
+//删除,并以op类型发布事件
+func (minic *minicache) delete(k string, op EventOp) {
+	item, found := minic.items.get(k)
+	if !found || minic.Frozen() {
+		return
+	}
+	minic.items.remove(k)
+	if minic.readMap != nil {
+		minic.readMap.Delete(k)
+	}
+	minic.totalCost -= item.cost
+	if op != EventEvict {
+		for _, sim := range minic.sims {
+			sim.remove(k)
+		}
+	}
+	//到期删除在回放时由过期时间还原,不需要记录
+	if op != EventExpire {
+		minic.logOp(aofDelete, k, Item{})
+	}
+	switch op {
+	case EventExpire:
+		minic.evicted(k, item.Object, ReasonExpired)
+	case EventEvict:
+		minic.evicted(k, item.Object, ReasonEvicted)
+	default:
+		minic.evicted(k, item.Object, ReasonDeleted)
+	}
+	if minic.dedup != nil {
+		minic.dedup.release(item.shared)
+	}
+	if ev := minic.evictor.Load(); ev != nil {
+		if op == EventEvict {
+			ev.evicted(k)
+		} else {
+			ev.remove(k)
+		}
+	}
+	if (op == EventExpire || op == EventEvict) && minic.deadEntries != nil && !item.read {
+		minic.deadEntries.record(k)
+	}
+	minic.unscope(k)
+	if minic.nsMetrics != nil {
+		minic.nsMetrics.entry(k, -1, -minic.sizedCost(item))
+	}
+	minic.publish(op, k, item)
+}
+
+//删除操作
+func (minic *minicache) Delete(k string) {
+	if minic.latency != nil {
+		defer minic.latency.since(LatencyDelete, time.Now())
+	}
+	if closed, _ := minic.closed(); closed {
+		return
+	}
+	minic.rwmtx.Lock()
+	defer minic.unlock()
+	minic.delete(k, EventDelete)
+}
+
+//解析实际使用的有效期,小于等于0表示永不过期
+func (minic *minicache) ttl(d time.Duration) time.Duration {
+	switch d {
+	case NoExpiration:
+		return 0
+	case DefaultExpiration:
+		return time.Duration(atomic.LoadInt64(&minic.defaultExpiration))
+	}
+	return d
+}
+
+//修改默认有效期,只影响之后以DefaultExpiration写入或续期的数据项,已有数据项的过期时间不变
+func (minic *minicache) SetDefaultExpiration(d time.Duration) {
+	atomic.StoreInt64(&minic.defaultExpiration, int64(d))
+}
+
+//按配置的比例随机调整有效期,避免同时写入的数据项同时过期
+func (minic *minicache) jitter(d time.Duration) time.Duration {
+	if minic.ttlJitter <= 0 || d <= 0 {
+		return d
+	}
+	delta := time.Duration((rand.Float64()*2 - 1) * minic.ttlJitter * float64(d))
+	if d+delta <= 0 {
+		return d
+	}
+	return d + delta
+}
+
+//把有效期限制在最大有效期内,永不过期同样被限制
+func (minic *minicache) clamp(d time.Duration) time.Duration {
+	if minic.maxTTL > 0 && (d <= 0 || d > minic.maxTTL) {
+		return minic.maxTTL
+	}
+	return d
+}
+
+//把绝对过期时间点限制在最大有效期内
+func (minic *minicache) clampAt(e int64) int64 {
+	if minic.maxTTL <= 0 {
+		return e
+	}
+	if limit := time.Now().Add(minic.maxTTL).UnixNano(); e == 0 || e > limit {
+		return limit
+	}
+	return e
+}
+
+//解析键k实际使用的有效期:临时覆盖、前缀默认有效期、缓存默认有效期、抖动和最大有效期,无锁
+func (minic *minicache) resolveTTL(k string, d time.Duration) time.Duration {
+	return minic.clamp(minic.jitter(minic.ttl(minic.prefixTTL(k, minic.overrideTTL(k, d)))))
+}
+
+//按与写入相同的有效期规则计算键k的过期时间点,0表示永不过期,无锁
+func (minic *minicache) expiration(k string, d time.Duration) int64 {
+	if d = minic.resolveTTL(k, d); d > 0 {
+		return minic.align(time.Now().Add(d).UnixNano())
+	}
+	return 0
 }
 
 //设置缓存数据项,存在就覆盖
-func (minic *Minicache) Set(k string, v interface{}, d time.Duration) {
-	var e int64
-	if d == defaultExpiration {
-		d = minic.defaultExpiration
+//延迟统计不使用defer;写锁仍用defer释放,Sizer等用户代码在锁内panic时不会一直持有锁
+func (minic *minicache) Set(k string, v interface{}, d time.Duration) error {
+	if minic.latency != nil {
+		start := time.Now()
+		err := minic.setLocked(k, v, d)
+		minic.latency.since(LatencySet, start)
+		return err
 	}
-	if d > 0 {
-		e = time.Now().Add(d).UnixNano()
+	return minic.setLocked(k, v, d)
+}
+
+func (minic *minicache) setLocked(k string, v interface{}, d time.Duration) error {
+	if closed, err := minic.closed(); closed {
+		return err
+	}
+	if over, err := minic.oversize(k, v); over {
+		return err
+	}
+	if minic.writes != nil {
+		return minic.enqueue(writeOp{k: k, v: v, d: d})
 	}
 	minic.rwmtx.Lock()
-	defer minic.rwmtx.Unlock()
-	minic.items[k] = Item{
+	defer minic.writeUnlock()
+	return minic.set(k, v, d)
+}
+
+//设置永不过期的缓存数据项
+func (minic *minicache) SetForever(k string, v interface{}) error {
+	return minic.Set(k, v, NoExpiration)
+}
+
+//设置缓存数据项,并在绝对时间点t过期,t为零值时永不过期
+func (minic *minicache) SetWithExpireAt(k string, v interface{}, t time.Time) error {
+	if closed, err := minic.closed(); closed {
+		return err
+	}
+	if over, err := minic.oversize(k, v); over {
+		return err
+	}
+	minic.rwmtx.Lock()
+	defer minic.writeUnlock()
+	return minic.store(k, Item{
 		Object:     v,
-		Expiration: e,
+		Expiration: minic.align(minic.clampAt(expireAt(t))),
+	})
+}
+
+//绝对时间点对应的过期时间,零值表示永不过期
+func expireAt(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
 	}
+	return t.UnixNano()
 }
 
 //设置数据项,无锁
-func (minic *Minicache) set(k string, v interface{}, d time.Duration) {
-	var e int64
-	if d == defaultExpiration {
-		d = minic.defaultExpiration
+func (minic *minicache) set(k string, v interface{}, d time.Duration) error {
+	return minic.setFrom(k, v, d, SourceSet)
+}
+
+//以指定来源设置数据项,无锁
+func (minic *minicache) setFrom(k string, v interface{}, d time.Duration, src ItemSource) error {
+	return minic.setItem(k, v, d, src, minic.sliding)
+}
+
+//设置数据项,sliding为true时按有效期滑动过期,无锁
+func (minic *minicache) setItem(k string, v interface{}, d time.Duration, src ItemSource, sliding bool) error {
+	return minic.store(k, minic.newItem(k, v, d, src, sliding))
+}
+
+//写入数据项,显式写入的新键先经过门卫过滤,再检查容量,无锁
+func (minic *minicache) store(k string, item Item) error {
+	return minic.storeGated(k, item, item.Source == SourceSet)
+}
+
+//恢复快照或回放日志时写入,不经过门卫,无锁
+func (minic *minicache) restore(k string, item Item) error {
+	return minic.storeGated(k, item, false)
+}
+
+//gated为true时新键需要通过门卫,未通过返回ErrNotAdmitted
+func (minic *minicache) storeGated(k string, item Item, gated bool) error {
+	//冻结前通过检查、在冻结后才拿到写锁的写操作
+	if minic.Frozen() {
+		return ErrReadOnly
+	}
+	if gated && !minic.passDoorkeeper(k) {
+		return fmt.Errorf("%w: %s", ErrNotAdmitted, k)
+	}
+	if err := minic.reserve(k, &item); err != nil {
+		return err
+	}
+	minic.put(k, item)
+	return nil
+}
+
+//按有效期规则构造数据项,无锁
+func (minic *minicache) newItem(k string, v interface{}, d time.Duration, src ItemSource, sliding bool) Item {
+	d = minic.resolveTTL(k, d)
+	item := Item{
+		Object: v,
+		Source: src,
 	}
 	if d > 0 {
-		e = time.Now().Add(d).UnixNano()
+		item.Expiration = minic.align(time.Now().Add(d).UnixNano())
+		if sliding {
+			item.Sliding = d
+		}
 	}
-	minic.items[k] = Item{
-		Object:     v,
-		Expiration: e,
+	return item
+}
+
+//设置滑动过期的缓存数据项,每次Get命中都会将过期时间顺延d
+func (minic *minicache) SetSliding(k string, v interface{}, d time.Duration) error {
+	if closed, err := minic.closed(); closed {
+		return err
+	}
+	if over, err := minic.oversize(k, v); over {
+		return err
+	}
+	minic.rwmtx.Lock()
+	defer minic.writeUnlock()
+	return minic.setItem(k, v, d, SourceSet, true)
+}
+
+//写入数据项并发布事件,无锁
+func (minic *minicache) put(k string, item Item) {
+	minic.unscope(k)
+	old, found := minic.items.get(k)
+	if found {
+		minic.evicted(k, old.Object, ReasonReplaced)
+	}
+	if minic.itemMetadata {
+		item.meta = &itemMeta{created: time.Now().UnixNano()}
+	}
+	if minic.dedup != nil {
+		item.Object, item.shared = minic.dedup.intern(item.Object)
+		if found {
+			minic.dedup.release(old.shared)
+		}
+	}
+	if item.cost <= 0 {
+		item.cost = minic.costOf(k, item.Object)
+	}
+	//快照和日志中的过期时间按存储精度取整,事件和日志中与存储的一致
+	item.Expiration = minic.items.round(item.Expiration)
+	minic.totalCost += item.cost - old.cost
+	if minic.nsMetrics != nil {
+		var n int64
+		if !found {
+			n = 1
+		}
+		minic.nsMetrics.entry(k, n, minic.sizedCost(item)-minic.sizedCost(old))
+	}
+	minic.storeItem(k, item)
+	minic.schedule(k, item.Expiration)
+	if minic.cardinality != nil {
+		minic.cardinality.add(k)
+	}
+	minic.publish(EventSet, k, item)
+	minic.logOp(aofSet, k, item)
+	for _, sim := range minic.sims {
+		sim.set(k)
+	}
+	if ev := minic.evictor.Load(); ev != nil {
+		if !minic.pinned(k) {
+			ev.add(k, item.priority)
+		}
+		minic.evict(k, !found)
 	}
 }
 
 //获取数据项,并判断数据项是否过期
-func (minic *Minicache) get(k string) (interface{}, bool) {
-	item, found := minic.items[k]
+func (minic *minicache) get(k string) (interface{}, bool) {
+	item, found := minic.items.get(k)
 	if !found || item.IsExpired() {
 		return nil, false
 	}
 	return item.Object, true
 }
 
-//新增操作,如果数据项存在,则报错
-func (minic *Minicache) Add(k string, v interface{}, d time.Duration) error {
+//新增操作,如果数据项存在,则返回ErrKeyExists
+func (minic *minicache) Add(k string, v interface{}, d time.Duration) error {
+	_, err := minic.AddOrGet(k, v, d)
+	return err
+}
+
+//新增操作,如果数据项存在,则返回已有值和ErrKeyExists,否则返回v
+func (minic *minicache) AddOrGet(k string, v interface{}, d time.Duration) (interface{}, error) {
+	if closed, err := minic.closed(); closed {
+		return nil, err
+	}
+	if over, err := minic.oversize(k, v); over {
+		return nil, err
+	}
 	minic.rwmtx.Lock()
-	_, found := minic.get(k)
+	defer minic.writeUnlock()
+	if existing, found := minic.get(k); found {
+		return existing, fmt.Errorf("%w: %s", ErrKeyExists, k)
+	}
+	if err := minic.set(k, v, d); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+//获取缓存操作
+//热路径上不使用defer,未命中和未开启的功能不产生内存分配
+func (minic *minicache) Get(k string) (interface{}, bool) {
+	if minic.latency != nil {
+		start := time.Now()
+		v, ok := minic.getTracked(k)
+		minic.latency.since(LatencyGet, start)
+		return v, ok
+	}
+	return minic.getTracked(k)
+}
+
+func (minic *minicache) getTracked(k string) (interface{}, bool) {
+	item, found := minic.lookup(k)
+	expired := found && item.IsExpired()
+	if expired {
+		if minic.gcInterval <= 0 {
+			minic.expireLazily(k)
+		}
+		found = false
+	}
+	if found && item.Sliding > 0 {
+		found = minic.slide(k)
+	}
+	if found && minic.deadEntries != nil && !item.read {
+		minic.markRead(k)
+	}
+	if found && item.meta != nil {
+		item.meta.access(time.Now().UnixNano())
+	}
+	if ev := minic.evictor.Load(); ev != nil {
+		if found {
+			ev.access(k)
+		} else {
+			ev.miss(k)
+		}
+	}
 	if found {
+		minic.publish(EventHit, k, item)
+	} else {
+		minic.publish(EventMiss, k, Item{})
+	}
+	if minic.nsMetrics != nil {
+		minic.nsMetrics.access(k, found)
+	}
+	if minic.cardinality != nil {
+		minic.cardinality.add(k)
+	}
+	if minic.hotKeys != nil {
+		minic.hotKeys.observe(k)
+	}
+	for _, sim := range minic.sims {
+		sim.get(k, found, expired)
+	}
+	if !found {
+		return nil, false
+	}
+	return item.Object, true
+}
+
+//没有后台gc时在访问时删除已过期(且超过保留期)的数据项
+func (minic *minicache) expireLazily(k string) {
+	if minic.Frozen() {
+		return
+	}
+	minic.rwmtx.Lock()
+	item, found := minic.items.get(k)
+	if !found || item.Expiration == 0 || time.Now().UnixNano() <= item.Expiration+int64(minic.expiredRetention) {
 		minic.rwmtx.Unlock()
-		return fmt.Errorf("Item %s already exists", k)
+		return
+	}
+	minic.delete(k, EventExpire)
+	onExpired := minic.onExpired
+	minic.unlock()
+	if onExpired != nil {
+		onExpired(k, item.Object)
 	}
-	minic.set(k, v, d)
-	minic.rwmtx.Unlock()
-	return nil
 }
 
-//获取缓存操作
-func (minic *Minicache) Get(k string) (interface{}, bool) {
+//顺延滑动过期数据项的过期时间,数据项在加锁前已过期或被删除时返回false
+func (minic *minicache) slide(k string) bool {
+	if minic.Frozen() {
+		return true
+	}
+	minic.rwmtx.Lock()
+	defer minic.rwmtx.Unlock()
+	item, found := minic.items.get(k)
+	if !found || item.IsExpired() {
+		return false
+	}
+	item.Expiration = minic.align(time.Now().Add(item.Sliding).UnixNano())
+	minic.storeItem(k, item)
+	minic.schedule(k, item.Expiration)
+	minic.publish(EventExpiration, k, item)
+	minic.logOp(aofExpire, k, item)
+	return true
+}
+
+//批量获取缓存,并在同一次加锁中延长命中数据项的有效期
+//缓存已冻结或关闭时只返回命中的数据项,不延长有效期
+func (minic *minicache) GetMultiTouch(keys []string, extend time.Duration) map[string]interface{} {
+	if closed, _ := minic.closed(); closed {
+		return minic.getMulti(keys)
+	}
+	minic.rwmtx.Lock()
+	defer minic.rwmtx.Unlock()
+	values := make(map[string]interface{}, len(keys))
+	for _, k := range keys {
+		item, found := minic.items.get(k)
+		expired := found && item.IsExpired()
+		for _, sim := range minic.sims {
+			sim.get(k, found && !expired, expired)
+		}
+		if !found || expired {
+			continue
+		}
+		e := minic.expiration(k, extend)
+		item.Expiration = e
+		item.read = true
+		if item.meta != nil {
+			item.meta.access(time.Now().UnixNano())
+		}
+		if ev := minic.evictor.Load(); ev != nil {
+			ev.access(k)
+		}
+		minic.storeItem(k, item)
+		minic.schedule(k, e)
+		minic.publish(EventExpiration, k, item)
+		minic.logOp(aofExpire, k, item)
+		values[k] = item.Object
+	}
+	return values
+}
+
+//批量读取未过期的数据项,不修改数据项
+func (minic *minicache) getMulti(keys []string) map[string]interface{} {
+	minic.rwmtx.RLock()
+	defer minic.rwmtx.RUnlock()
+	values := make(map[string]interface{}, len(keys))
+	for _, k := range keys {
+		item, found := minic.items.get(k)
+		expired := found && item.IsExpired()
+		for _, sim := range minic.sims {
+			sim.get(k, found && !expired, expired)
+		}
+		if found && !expired {
+			values[k] = item.Object
+		}
+	}
+	return values
+}
+
+//获取缓存,已过期但尚未被清理的数据项也会返回,expired标识是否过期
+func (minic *minicache) GetStale(k string) (v interface{}, expired bool, found bool) {
 	minic.rwmtx.RLock()
-	item, found := minic.items[k]
+	item, found := minic.items.get(k)
+	minic.rwmtx.RUnlock()
+	expired = found && item.IsExpired()
+	for _, sim := range minic.sims {
+		sim.get(k, found && !expired, expired)
+	}
+	if !found {
+		return nil, false, false
+	}
+	return item.Object, expired, true
+}
+
+//重新确认数据项仍然有效,以有效期d续期,包括保留期内已过期的数据项,数据项不存在时返回false
+//用于在后端确认GetStale返回的旧值未变化后直接恢复该数据项,而不必重新写入
+func (minic *minicache) Revalidate(k string, d time.Duration) bool {
+	if closed, _ := minic.closed(); closed {
+		return false
+	}
+	minic.rwmtx.Lock()
+	defer minic.rwmtx.Unlock()
+	item, found := minic.items.get(k)
+	if !found {
+		return false
+	}
+	minic.renew(k, item, minic.expiration(k, d))
+	return true
+}
+
+//按默认有效期重置数据项的过期时间,不改写数据,前缀默认有效期和临时覆盖规则同样适用
+func (minic *minicache) Touch(k string) bool {
+	return minic.updateExpiration(k, func() int64 { return minic.expiration(k, DefaultExpiration) })
+}
+
+//只修改数据项的有效期,不改写数据
+func (minic *minicache) Expire(k string, d time.Duration) bool {
+	return minic.updateExpiration(k, func() int64 { return minic.expiration(k, d) })
+}
+
+//将数据项的过期时间设为绝对时间点t
+func (minic *minicache) ExpireAt(k string, t time.Time) bool {
+	return minic.updateExpiration(k, func() int64 { return expireAt(t) })
+}
+
+//去掉数据项的有效期,使其永不过期
+func (minic *minicache) Persist(k string) bool {
+	return minic.updateExpiration(k, func() int64 { return 0 })
+}
+
+//只更新未过期数据项的过期时间点,滑动过期数据项的滑动时长随之调整,at在写锁内计算过期时间点
+func (minic *minicache) updateExpiration(k string, at func() int64) bool {
+	if closed, _ := minic.closed(); closed {
+		return false
+	}
+	minic.rwmtx.Lock()
+	defer minic.rwmtx.Unlock()
+	item, found := minic.items.get(k)
 	if !found || item.IsExpired() {
-		minic.rwmtx.RUnlock()
-		return nil, false
+		return false
+	}
+	minic.renew(k, item, minic.align(minic.clampAt(at())))
+	return true
+}
+
+//将数据项的过期时间改为e,滑动过期数据项的滑动时长随之调整,无锁
+func (minic *minicache) renew(k string, item Item, e int64) {
+	item.Expiration = e
+	if e == 0 {
+		item.Sliding = 0
+	} else if item.Sliding > 0 {
+		item.Sliding = time.Duration(e - time.Now().UnixNano())
 	}
+	minic.storeItem(k, item)
+	minic.schedule(k, e)
+	minic.publish(EventExpiration, k, item)
+	minic.logOp(aofExpire, k, item)
+}
+
+//返回数据项的原始存储内容,包括已过期但尚未清理的数据项,用于诊断
+func (minic *minicache) Inspect(k string) (Item, bool) {
+	minic.rwmtx.RLock()
+	defer minic.rwmtx.RUnlock()
+	item, found := minic.items.get(k)
+	return item, found
+}
+
+//返回数据项的剩余有效期,永不过期的数据项返回NoExpiration
+func (minic *minicache) TTL(k string) (time.Duration, bool) {
+	minic.rwmtx.RLock()
+	item, found := minic.items.get(k)
 	minic.rwmtx.RUnlock()
-	return item.Object, true
+	if !found || item.IsExpired() {
+		return 0, false
+	}
+	if item.Expiration == 0 {
+		return NoExpiration, true
+	}
+	return time.Duration(item.Expiration - time.Now().UnixNano()), true
+}
+
+//返回未来d时长内将要过期的键,按过期时间从早到晚排序,已过期和永不过期的数据项不包含在内
+func (minic *minicache) ExpiringWithin(d time.Duration) []string {
+	now := time.Now().UnixNano()
+	return sortExpiring(minic.expiring(nil, now, now+int64(d)))
+}
+
+//将要过期的键及其过期时间
+type expiringKey struct {
+	key        string
+	expiration int64
+}
+
+//把过期时间在(now, deadline]内的键追加到list
+func (minic *minicache) expiring(list []expiringKey, now, deadline int64) []expiringKey {
+	minic.rwmtx.RLock()
+	defer minic.rwmtx.RUnlock()
+	minic.items.each(func(k string, v Item) bool {
+		if v.Expiration > now && v.Expiration <= deadline {
+			list = append(list, expiringKey{k, v.Expiration})
+		}
+		return true
+	})
+	return list
+}
+
+//按过期时间从早到晚排序,返回键
+func sortExpiring(list []expiringKey) []string {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].expiration != list[j].expiration {
+			return list[i].expiration < list[j].expiration
+		}
+		return list[i].key < list[j].key
+	})
+	keys := make([]string, len(list))
+	for i, e := range list {
+		keys[i] = e.key
+	}
+	return keys
 }
 
 //替换缓存
-func (minic *Minicache) Replace(k string, v interface{}, d time.Duration) error {
+func (minic *minicache) Replace(k string, v interface{}, d time.Duration) error {
+	if closed, err := minic.closed(); closed {
+		return err
+	}
+	if over, err := minic.oversize(k, v); over {
+		return err
+	}
 	minic.rwmtx.Lock()
 	_, found := minic.get(k)
 	if !found {
 		minic.rwmtx.Unlock()
 		return fmt.Errorf("Item %s does not exists", k)
 	}
-	minic.set(k, v, d)
-	minic.rwmtx.Unlock()
-	return nil
+	err := minic.set(k, v, d)
+	minic.writeUnlock()
+	return err
+}
+
+//数据项不存在时写入v,存在时写入merge(existing, v)
+//大小上限按合并后的值检查,超过时按WithMaxItemSize的策略处理,原有的数据项保持不变
+func (minic *minicache) Upsert(k string, v interface{}, d time.Duration, merge func(existing, new interface{}) interface{}) error {
+	if closed, err := minic.closed(); closed {
+		return err
+	}
+	minic.rwmtx.Lock()
+	defer minic.writeUnlock()
+	if existing, found := minic.get(k); found {
+		v = merge(existing, v)
+	}
+	//检查合并后实际写入的值,累加类的merge不会绕过大小上限
+	if over, err := minic.oversize(k, v); over {
+		return err
+	}
+	return minic.set(k, v, d)
 }
 
-//缓存数据写入io.Writer中
-func (minic *Minicache) Save(w io.Writer) (err error) {
-	enc := gob.NewEncoder(w)
+//抢占键的所有权,键不存在时记录ownerID并返回true,始终返回当前持有者
+//持有者就是ownerID时同样返回true,但不刷新有效期
+func (minic *minicache) Claim(k, ownerID string, d time.Duration) (currentOwner string, acquired bool) {
+	if closed, _ := minic.closed(); closed {
+		return "", false
+	}
+	minic.rwmtx.Lock()
+	defer minic.writeUnlock()
+	if existing, found := minic.get(k); found {
+		currentOwner, _ = existing.(string)
+		return currentOwner, currentOwner == ownerID
+	}
+	if minic.set(k, ownerID, d) != nil {
+		return "", false
+	}
+	return ownerID, true
+}
+
+//数值大于当前值时写入,数据项不存在时直接写入
+func (minic *minicache) SetIfGreater(k string, v int64, d time.Duration) (updated bool) {
+	return minic.setIf(k, v, d, func(old int64) bool { return v > old })
+}
+
+//数值小于当前值时写入,数据项不存在时直接写入
+func (minic *minicache) SetIfLess(k string, v int64, d time.Duration) (updated bool) {
+	return minic.setIf(k, v, d, func(old int64) bool { return v < old })
+}
+
+//条件写入,当前值不是int64时不写入
+func (minic *minicache) setIf(k string, v int64, d time.Duration, cond func(old int64) bool) bool {
+	if closed, _ := minic.closed(); closed {
+		return false
+	}
+	minic.rwmtx.Lock()
+	defer minic.writeUnlock()
+	if obj, found := minic.get(k); found {
+		old, ok := obj.(int64)
+		if !ok || !cond(old) {
+			return false
+		}
+	}
+	return minic.set(k, v, d) == nil
+}
+
+//缓存数据写入io.Writer中,数据前写入文件头,末尾附加校验和,开启WithSnapshotCompression时压缩数据部分
+//开启WithSnapshotEncryption时加密数据部分,文件头不加密但参与认证
+//只在复制数据项时短暂持有读锁,编码和写入在锁外进行,不阻塞读写;值本身不复制,保存期间不应原地修改缓存中的值
+func (minic *minicache) Save(w io.Writer) error {
+	return minic.encode(w, minic.snapshot())
+}
+
+//按缓存的压缩和加密配置编码数据项,无锁
+func (minic *minicache) encode(w io.Writer, items map[string]Item) (err error) {
 	defer func() {
 		if x := recover(); x != nil {
 			err = fmt.Errorf("Error registering item types with gob library")
 		}
 	}()
-	minic.rwmtx.Lock()
-	defer minic.rwmtx.Unlock()
-	for _, v := range minic.items {
+	for _, v := range items {
 		gob.Register(v.Object)
 	}
-	err = enc.Encode(&minic.items)
+	err = encodeSnapshot(w, items, minic.snapCompression, minic.snapLevel, minic.snapKeys)
 	return
 }
 
-//序列化到文件
-func (minic *Minicache) SaveToFile(fileName string) error {
-	f, err := os.Create(fileName)
+//在读锁下复制数据项
+func (minic *minicache) snapshot() map[string]Item {
+	minic.rwmtx.RLock()
+	defer minic.rwmtx.RUnlock()
+	items := make(map[string]Item, minic.items.len())
+	minic.items.each(func(k string, v Item) bool {
+		items[k] = v
+		return true
+	})
+	return items
+}
+
+//序列化到文件,先写入同一目录下的临时文件并同步到磁盘,再原子地重命名为fileName
+//写入中途失败或崩溃时原有的文件保持不变;覆盖已有文件时沿用其权限,新文件的权限为0600
+func (minic *minicache) SaveToFile(fileName string) error {
+	return writeFileAtomic(fileName, minic.Save)
+}
+
+func writeFileAtomic(fileName string, save func(io.Writer) error) error {
+	f, err := os.CreateTemp(filepath.Dir(fileName), "."+filepath.Base(fileName)+".tmp-*")
 	if err != nil {
 		return err
 	}
-	if err = minic.Save(f); err != nil {
-		f.Close()
+	tmp := f.Name()
+	if fi, statErr := os.Stat(fileName); statErr == nil {
+		f.Chmod(fi.Mode().Perm())
+	}
+	if err = save(f); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, fileName)
+	}
+	if err != nil {
+		os.Remove(tmp)
 		return err
 	}
-	return f.Close()
+	syncDir(filepath.Dir(fileName))
+	return nil
+}
+
+//同步目录,使重命名在崩溃后仍然有效,不支持时忽略
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
 }
 
-//从io.Reader读取
-func (minic *Minicache) Load(r io.Reader) error {
-	dec := gob.NewDecoder(r)
-	items := make(map[string]Item, 0)
-	err := dec.Decode(&items)
+//从io.Reader读取,解码在锁外完成,合并按批次加锁
+//校验文件头,不是快照时返回ErrNotASnapshot,格式版本不支持时返回ErrUnsupportedVersion,没有文件头的旧快照仍可读取
+//校验和不一致时返回ErrCorruptSnapshot,解密失败时返回ErrDecryptSnapshot,均不合并任何数据项
+func (minic *minicache) Load(r io.Reader) error {
+	if closed, err := minic.closed(); closed {
+		return err
+	}
+	items, err := decodeSnapshot(r, minic.snapKeys)
 	if err != nil {
 		return err
 	}
-	minic.rwmtx.Lock()
-	defer minic.rwmtx.Unlock()
+	minic.merge(items)
+	return nil
+}
+
+//合并解码出的数据项,已有的未过期数据项优先,按批次加锁
+func (minic *minicache) merge(items map[string]Item) {
+	keys := make([]string, 0, len(items))
 	for k, v := range items {
-		obj, ok := minic.items[k]
-		if !ok || obj.IsExpired() {
-			minic.items[k] = v
+		if over, _ := minic.oversize(k, v.Object); !over {
+			keys = append(keys, k)
 		}
 	}
-	return nil
+	//分批合并,批次之间释放锁,避免长时间阻塞读写
+	for len(keys) > 0 {
+		n := minic.loadBatchSize
+		if n <= 0 || n > len(keys) {
+			n = len(keys)
+		}
+		minic.rwmtx.Lock()
+		for _, k := range keys[:n] {
+			obj, ok := minic.items.get(k)
+			if !ok || obj.IsExpired() {
+				v := items[k]
+				v.Source = SourceSnapshot
+				minic.restore(k, v)
+			}
+		}
+		minic.unlock()
+		keys = keys[n:]
+	}
 }
 
 //从文件中读取
-func (minic *Minicache) LoadFromFile(fileName string) error {
+func (minic *minicache) LoadFromFile(fileName string) error {
+	return readFile(fileName, minic.Load)
+}
+
+func readFile(fileName string, load func(io.Reader) error) error {
 	f, err := os.Open(fileName)
 	if err != nil {
 		return err
 	}
-	if err = minic.Load(f); err != nil {
+	if err = load(f); err != nil {
 		f.Close()
 		return err
 	}
@@ -214,32 +1285,116 @@ func (minic *Minicache) LoadFromFile(fileName string) error {
 }
 
 //返回缓存中数据项数量
-func (minic *Minicache) Count() int {
+func (minic *minicache) Count() int {
 	minic.rwmtx.Lock()
 	defer minic.rwmtx.Unlock()
-	return len(minic.items)
+	return minic.items.len()
 }
 
 //清空缓存
-func (minic *Minicache) Flush() {
-	minic.rwmtx.RLock()
-	defer minic.rwmtx.RUnlock()
-	minic.items = map[string]Item{}
+func (minic *minicache) Flush() {
+	if closed, _ := minic.closed(); closed {
+		return
+	}
+	minic.rwmtx.Lock()
+	defer minic.unlock()
+	if minic.Frozen() {
+		return
+	}
+	minic.flush()
+}
+
+//清空缓存,无锁
+func (minic *minicache) flush() {
+	if minic.onEvicted != nil {
+		minic.items.each(func(k string, v Item) bool {
+			minic.evicted(k, v.Object, ReasonFlushed)
+			return true
+		})
+	}
+	minic.items.reset(minic.initialCapacity)
+	if minic.readMap != nil {
+		minic.readMap.Range(func(k, _ interface{}) bool {
+			minic.readMap.Delete(k)
+			return true
+		})
+	}
+	minic.totalCost = 0
+	if minic.dedup != nil {
+		minic.dedup.reset()
+	}
+	if ev := minic.evictor.Load(); ev != nil {
+		ev.reset()
+	}
+	for _, sim := range minic.sims {
+		sim.reset()
+	}
+	minic.expiries.reset()
+	minic.notices.reset()
+	for k := range minic.scopes {
+		minic.unscope(k)
+	}
+	if minic.nsMetrics != nil {
+		minic.nsMetrics.reset()
+	}
+	minic.publish(EventFlush, "", Item{})
+	minic.logOp(aofFlush, "", Item{})
 }
 
 //停止gc
-func (minic *Minicache) Stopgc() {
-	minic.stopGc <- true
+func (minic *minicache) Stopgc() {
+	if minic.gcInterval <= 0 {
+		return
+	}
+	minic.stopOnce.Do(func() {
+		close(minic.stopGc)
+	})
+}
+
+//预先为n个数据项分配map空间,避免启动后集中写入时map反复扩容,Flush后按同样的大小重新分配
+//用于ShardedCache时应按每个分片的预期数量设置
+func WithInitialCapacity(n int) Option {
+	return func(minic *Minicache) {
+		minic.initialCapacity = n
+	}
 }
 
-//创建缓存
-func NewMiniCache(defaultExpiration, gcInterval time.Duration) (minic *Minicache) {
-	minic = &Minicache{
-		defaultExpiration: defaultExpiration,
+//创建缓存,gcInterval小于等于0时不启动后台gc,过期数据项在访问时删除
+func NewMiniCache(defaultExpiration, gcInterval time.Duration, opts ...Option) (minic *Minicache) {
+	minic = &Minicache{&minicache{
+		defaultExpiration: int64(defaultExpiration),
 		gcInterval:        gcInterval,
-		items:             map[string]Item{},
 		stopGc:            make(chan bool),
+		events:            &eventHub{},
+		done:              make(chan struct{}),
+		loadBatchSize:     1000,
+	}}
+	for _, opt := range opts {
+		opt(minic)
+	}
+	minic.items.reset(minic.initialCapacity)
+	if minic.gcBatchSize <= 0 {
+		minic.gcBatchSize = 1000
+	}
+	minic.initBounds()
+	if minic.writes != nil {
+		minic.background.Add(1)
+		go minic.drainWrites()
+	}
+	if gcInterval > 0 {
+		minic.background.Add(1)
+		go minic.gcLoop()
+	}
+	if minic.aofFile != "" {
+		minic.openAOF()
+	}
+	if minic.autoSaveInterval > 0 && minic.persistFile != "" {
+		minic.autoSaved(minic.ValidatePersistence(minic.persistFile))
+		minic.background.Add(1)
+		go minic.autoSaveLoop()
 	}
-	go minic.gcLoop()
+	runtime.SetFinalizer(minic, func(minic *Minicache) {
+		minic.Close()
+	})
 	return
 }
@@ -0,0 +1,60 @@
+package minicache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetExpiration(t *testing.T) {
+	tests := []struct {
+		name       string
+		defaultExp time.Duration
+		set        func(c *Minicache) error
+		want       time.Duration //0表示永不过期
+	}{
+		{"NoExpiration", 0, func(c *Minicache) error { return c.Set("k", 1, NoExpiration) }, 0},
+		{"NoExpirationBypassesDefault", time.Minute, func(c *Minicache) error { return c.Set("k", 1, NoExpiration) }, 0},
+		{"DefaultExpirationWithoutDefault", 0, func(c *Minicache) error { return c.Set("k", 1, DefaultExpiration) }, 0},
+		{"DefaultExpiration", time.Minute, func(c *Minicache) error { return c.Set("k", 1, DefaultExpiration) }, time.Minute},
+		{"Duration", time.Minute, func(c *Minicache) error { return c.Set("k", 1, time.Hour) }, time.Hour},
+		{"SetForever", time.Minute, func(c *Minicache) error { return c.SetForever("k", 1) }, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewMiniCache(tc.defaultExp, 0)
+			defer c.Close()
+			start := time.Now()
+			if err := tc.set(c); err != nil {
+				t.Fatal(err)
+			}
+			item, found := c.Inspect("k")
+			if !found {
+				t.Fatal("item not found")
+			}
+			if tc.want == 0 {
+				if item.Expiration != 0 {
+					t.Fatalf("Expiration = %v, want never", time.Unix(0, item.Expiration))
+				}
+				return
+			}
+			got := time.Unix(0, item.Expiration).Sub(start)
+			if got < tc.want || got > tc.want+time.Second {
+				t.Fatalf("expires after %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSetExpires(t *testing.T) {
+	c := NewMiniCache(time.Hour, 0)
+	defer c.Close()
+	c.Set("short", 1, 10*time.Millisecond)
+	c.Set("forever", 2, NoExpiration)
+	time.Sleep(20 * time.Millisecond)
+	if _, found := c.Get("short"); found {
+		t.Fatal("short should have expired")
+	}
+	if v, found := c.Get("forever"); !found || v != 2 {
+		t.Fatalf(`Get("forever") = %v, %v`, v, found)
+	}
+}
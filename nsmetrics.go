@@ -0,0 +1,102 @@
+package minicache
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+//命名空间统计
+type NamespaceStats struct {
+	Hits    uint64
+	Misses  uint64
+	Entries int64
+	Bytes   int64 //数据项的估计内存占用之和,只在配置了WithMaxMemory或WithSizer时统计
+}
+
+//单个命名空间的计数器
+type nsCounter struct {
+	prefix  string
+	hits    uint64
+	misses  uint64
+	entries int64
+	bytes   int64
+}
+
+//按键前缀划分的统计,前缀集合在创建时固定以限制基数
+type nsMetrics struct {
+	namespaces []*nsCounter
+	other      nsCounter
+}
+
+//按键前缀分别统计命中、未命中、数据项数和估计的内存占用,未匹配任何前缀的键计入空字符串命名空间
+func WithNamespaceMetrics(prefixes ...string) Option {
+	return func(minic *Minicache) {
+		m := &nsMetrics{}
+		for _, p := range prefixes {
+			if p != "" {
+				m.namespaces = append(m.namespaces, &nsCounter{prefix: p})
+			}
+		}
+		minic.nsMetrics = m
+	}
+}
+
+//查找键所属的命名空间,以最长前缀为准
+func (m *nsMetrics) lookup(k string) *nsCounter {
+	c := &m.other
+	for _, ns := range m.namespaces {
+		if len(ns.prefix) > len(c.prefix) && strings.HasPrefix(k, ns.prefix) {
+			c = ns
+		}
+	}
+	return c
+}
+
+func (m *nsMetrics) access(k string, hit bool) {
+	if hit {
+		atomic.AddUint64(&m.lookup(k).hits, 1)
+	} else {
+		atomic.AddUint64(&m.lookup(k).misses, 1)
+	}
+}
+
+func (m *nsMetrics) entry(k string, delta, bytes int64) {
+	c := m.lookup(k)
+	atomic.AddInt64(&c.entries, delta)
+	if bytes != 0 {
+		atomic.AddInt64(&c.bytes, bytes)
+	}
+}
+
+func (m *nsMetrics) reset() {
+	for _, c := range append([]*nsCounter{&m.other}, m.namespaces...) {
+		atomic.StoreInt64(&c.entries, 0)
+		atomic.StoreInt64(&c.bytes, 0)
+	}
+}
+
+//数据项计入命名空间Bytes的大小,没有配置Sizer时开销不代表内存占用,不统计,无锁
+func (minic *minicache) sizedCost(item Item) int64 {
+	if minic.sizer == nil {
+		return 0
+	}
+	return item.cost
+}
+
+//返回各命名空间的统计,未开启时返回nil
+func (minic *minicache) NamespaceStats() map[string]NamespaceStats {
+	m := minic.nsMetrics
+	if m == nil {
+		return nil
+	}
+	stats := make(map[string]NamespaceStats, len(m.namespaces)+1)
+	for _, c := range append([]*nsCounter{&m.other}, m.namespaces...) {
+		stats[c.prefix] = NamespaceStats{
+			Hits:    atomic.LoadUint64(&c.hits),
+			Misses:  atomic.LoadUint64(&c.misses),
+			Entries: atomic.LoadInt64(&c.entries),
+			Bytes:   atomic.LoadInt64(&c.bytes),
+		}
+	}
+	return stats
+}
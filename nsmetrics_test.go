@@ -0,0 +1,47 @@
+package minicache
+
+import (
+	"strings"
+	"testing"
+)
+
+type lenSizer struct{}
+
+func (lenSizer) Size(v interface{}) int64 {
+	return int64(len(v.(string)))
+}
+
+func TestNamespaceBytes(t *testing.T) {
+	c := NewMiniCache(0, 0, WithNamespaceMetrics("user:", "img:"), WithSizer(lenSizer{}))
+	defer c.Close()
+	c.Set("user:1", "ab", 0)
+	c.Set("img:1", strings.Repeat("x", 100), 0)
+	c.Set("img:2", strings.Repeat("x", 50), 0)
+	c.Set("img:2", strings.Repeat("x", 10), 0)
+	c.Set("other", "", 0)
+	c.Delete("img:1")
+
+	stats := c.NamespaceStats()
+	for ns, want := range map[string]int64{
+		"user:": c.costOf("user:1", "ab"),
+		"img:":  c.costOf("img:2", strings.Repeat("x", 10)),
+		"":      c.costOf("other", ""),
+	} {
+		if got := stats[ns].Bytes; got != want {
+			t.Errorf("NamespaceStats()[%q].Bytes = %d, want %d", ns, got, want)
+		}
+	}
+	c.Flush()
+	if b := c.NamespaceStats()["img:"].Bytes; b != 0 {
+		t.Fatalf("Bytes after Flush = %d", b)
+	}
+}
+
+func TestNamespaceBytesWithoutSizer(t *testing.T) {
+	c := NewMiniCache(0, 0, WithNamespaceMetrics("a:"))
+	defer c.Close()
+	c.Set("a:1", "v", 0)
+	if st := c.NamespaceStats()["a:"]; st.Entries != 1 || st.Bytes != 0 {
+		t.Fatalf("NamespaceStats()[a:] = %+v, want 1 entry and no bytes", st)
+	}
+}
@@ -0,0 +1,8 @@
+package minicache
+
+import "syscall"
+
+//将映射内存的物理页归还给系统,地址范围保持可用,再次写入时按需分配
+func releaseOffHeap(b []byte) {
+	syscall.Madvise(b, syscall.MADV_DONTNEED)
+}
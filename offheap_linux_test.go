@@ -0,0 +1,48 @@
+package minicache
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOffHeapSlab(t *testing.T) {
+	sc, err := NewOffHeapSlabCache(NoExpiration, 2, 4096)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sc.Set("a", []byte("value"), 0); err != nil {
+		t.Fatal(err)
+	}
+	v, ok := sc.Get("a")
+	if !ok || string(v) != "value" {
+		t.Fatalf("Get(a) = %q, %v", v, ok)
+	}
+	to, _ := sc.GetTo("a", nil)
+	sc.Flush()
+	if _, ok := sc.Get("a"); ok || sc.Count() != 0 {
+		t.Fatal("Flush left items behind")
+	}
+	//Flush归还物理页后映射仍然可写
+	if err := sc.Set("b", []byte("again"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := sc.Get("b"); !ok || string(v) != "again" {
+		t.Fatalf("Get(b) after Flush = %q, %v", v, ok)
+	}
+	if err := sc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sc.Close(); err != nil {
+		t.Fatalf("second Close = %v", err)
+	}
+	//读取结果是副本,解除映射后仍可访问
+	if string(v) != "value" || string(to) != "value" {
+		t.Fatalf("results changed after Close: %q, %q", v, to)
+	}
+	if _, ok := sc.Get("b"); ok {
+		t.Fatal("Get after Close found an item")
+	}
+	if err := sc.Set("c", []byte("1"), 0); !errors.Is(err, ErrCacheClosed) {
+		t.Fatalf("Set after Close = %v, want ErrCacheClosed", err)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+
+package minicache
+
+import "errors"
+
+//当前平台不支持堆外内存
+func mapOffHeap(size int) ([]byte, error) {
+	return nil, errors.New("off-heap storage is not supported on this platform")
+}
+
+func unmapOffHeap(b []byte) error {
+	return nil
+}
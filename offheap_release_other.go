@@ -0,0 +1,6 @@
+//go:build !linux
+
+package minicache
+
+//当前平台不归还物理页,内存在Close时释放
+func releaseOffHeap(b []byte) {}
@@ -0,0 +1,15 @@
+//go:build linux || darwin
+
+package minicache
+
+import "syscall"
+
+//分配size字节的匿名映射内存,不在Go堆上
+func mapOffHeap(size int) ([]byte, error) {
+	return syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+}
+
+//释放映射内存
+func unmapOffHeap(b []byte) error {
+	return syscall.Munmap(b)
+}
@@ -0,0 +1,66 @@
+package minicache
+
+//数据项离开缓存的原因
+type EvictionReason int
+
+const (
+	ReasonExpired  EvictionReason = iota //过期后被gc或惰性删除
+	ReasonEvicted                        //超出容量被淘汰
+	ReasonDeleted                        //被显式删除
+	ReasonReplaced                       //被新值覆盖
+	ReasonFlushed                        //被Flush清空
+
+	reasonCount = iota
+)
+
+func (r EvictionReason) String() string {
+	switch r {
+	case ReasonExpired:
+		return "expired"
+	case ReasonEvicted:
+		return "evicted"
+	case ReasonDeleted:
+		return "deleted"
+	case ReasonReplaced:
+		return "replaced"
+	case ReasonFlushed:
+		return "flushed"
+	}
+	return "unknown"
+}
+
+//等待在锁外回调的离开缓存的数据项
+type evictedEntry struct {
+	key    string
+	object interface{}
+	reason EvictionReason
+}
+
+//注册数据项离开缓存的回调,在释放写锁后按发生顺序调用,再次注册会替换之前的回调
+func (minic *minicache) OnEvicted(fn func(k string, v interface{}, reason EvictionReason)) {
+	minic.rwmtx.Lock()
+	defer minic.rwmtx.Unlock()
+	minic.onEvicted = fn
+}
+
+//记录离开缓存的数据项并计数,注册了回调时留待释放锁后回调,无锁
+func (minic *minicache) evicted(k string, v interface{}, reason EvictionReason) {
+	minic.removals[reason]++
+	if minic.onEvicted != nil {
+		minic.evictedPending = append(minic.evictedPending, evictedEntry{key: k, object: v, reason: reason})
+	}
+}
+
+//释放写锁,然后在锁外执行期间积累的OnEvicted和OnExpired回调
+func (minic *minicache) unlock() {
+	pending, expired := minic.evictedPending, minic.expiredPending
+	minic.evictedPending, minic.expiredPending = nil, nil
+	fn, onExpired := minic.onEvicted, minic.onExpired
+	minic.rwmtx.Unlock()
+	for _, e := range pending {
+		fn(e.key, e.object, e.reason)
+	}
+	for _, e := range expired {
+		onExpired(e.key, e.object)
+	}
+}
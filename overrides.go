@@ -0,0 +1,50 @@
+package minicache
+
+import (
+	"strings"
+	"time"
+)
+
+//临时有效期覆盖规则
+type ttlOverride struct {
+	d     time.Duration
+	until int64
+}
+
+//临时覆盖键或键前缀的有效期,until之前Set该键族时使用d作为有效期
+//同一键匹配多条规则时以最长前缀为准
+func (minic *minicache) OverrideTTL(keyOrPrefix string, d time.Duration, until time.Time) {
+	minic.rwmtx.Lock()
+	defer minic.rwmtx.Unlock()
+	if minic.overrides == nil {
+		minic.overrides = map[string]ttlOverride{}
+	}
+	minic.overrides[keyOrPrefix] = ttlOverride{d: d, until: until.UnixNano()}
+}
+
+//移除有效期覆盖规则
+func (minic *minicache) ClearTTLOverride(keyOrPrefix string) {
+	minic.rwmtx.Lock()
+	defer minic.rwmtx.Unlock()
+	delete(minic.overrides, keyOrPrefix)
+}
+
+//查找生效的覆盖规则,顺带清理已失效的规则,无锁
+func (minic *minicache) overrideTTL(k string, d time.Duration) time.Duration {
+	if len(minic.overrides) == 0 {
+		return d
+	}
+	now := time.Now().UnixNano()
+	matched := -1
+	for prefix, o := range minic.overrides {
+		if now > o.until {
+			delete(minic.overrides, prefix)
+			continue
+		}
+		if len(prefix) > matched && strings.HasPrefix(k, prefix) {
+			matched = len(prefix)
+			d = o.d
+		}
+	}
+	return d
+}
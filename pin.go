@@ -0,0 +1,36 @@
+package minicache
+
+//固定键,被固定的键不会因容量限制被淘汰,但仍会按有效期过期和被删除
+//固定针对键而不是当前的数据项,在Unpin之前重新写入的数据项同样被固定
+func (minic *minicache) Pin(k string) {
+	minic.rwmtx.Lock()
+	defer minic.rwmtx.Unlock()
+	if minic.pins == nil {
+		minic.pins = map[string]struct{}{}
+	}
+	minic.pins[k] = struct{}{}
+	if ev := minic.evictor.Load(); ev != nil {
+		ev.remove(k)
+	}
+}
+
+//取消固定,数据项重新参与淘汰
+func (minic *minicache) Unpin(k string) {
+	minic.rwmtx.Lock()
+	defer minic.unlock()
+	if _, ok := minic.pins[k]; !ok || minic.Frozen() {
+		return
+	}
+	delete(minic.pins, k)
+	ev := minic.evictor.Load()
+	if item, found := minic.items.get(k); found && ev != nil {
+		ev.add(k, item.priority)
+		minic.evict(k, false)
+	}
+}
+
+//键是否被固定,无锁
+func (minic *minicache) pinned(k string) bool {
+	_, ok := minic.pins[k]
+	return ok
+}
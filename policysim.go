@@ -0,0 +1,118 @@
+package minicache
+
+import "sync"
+
+//模拟淘汰策略的命中统计
+type SimulationStats struct {
+	Policy     EvictionPolicy
+	MaxEntries int
+	Hits       uint64 //模拟缓存的命中次数
+	Misses     uint64
+	RealHits   uint64 //同一时间段真实缓存的命中次数
+	RealMisses uint64
+}
+
+//模拟缓存的命中率
+func (s SimulationStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+//真实缓存的命中率
+func (s SimulationStats) RealHitRate() float64 {
+	if s.RealHits+s.RealMisses == 0 {
+		return 0
+	}
+	return float64(s.RealHits) / float64(s.RealHits+s.RealMisses)
+}
+
+//只记录键的模拟缓存,与真实缓存接收相同的读写和删除,按自己的策略和容量淘汰
+type policySim struct {
+	mtx      sync.Mutex
+	stats    SimulationStats
+	evictor  *evictor
+	resident map[string]struct{}
+}
+
+//在真实缓存旁模拟另一种淘汰策略或容量,通过SimulationReport比较命中率,可多次使用以同时模拟多种配置
+//模拟缓存不保存值,只在自己的锁下维护键,过期和删除与真实缓存同步
+//maxEntries<=0时模拟缓存不限容量,可用于估计真实缓存因容量不足损失的命中
+//Get、GetMultiTouch、GetWithMeta和GetStale的读取都计入模拟,GetOrLoad和Memoize通过Get计入,加载的结果作为写入
+func WithPolicySimulation(p EvictionPolicy, maxEntries int) Option {
+	return func(minic *Minicache) {
+		minic.sims = append(minic.sims, &policySim{
+			stats:    SimulationStats{Policy: p, MaxEntries: maxEntries},
+			evictor:  newEvictor(p, maxEntries, false),
+			resident: map[string]struct{}{},
+		})
+	}
+}
+
+//expired表示真实缓存中的数据项已过期,模拟缓存中同一个键也视为已过期
+func (s *policySim) get(k string, realHit, expired bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if expired {
+		s.drop(k)
+	}
+	if realHit {
+		s.stats.RealHits++
+	} else {
+		s.stats.RealMisses++
+	}
+	if _, ok := s.resident[k]; ok {
+		s.stats.Hits++
+		s.evictor.access(k)
+	} else {
+		s.stats.Misses++
+	}
+}
+
+func (s *policySim) set(k string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.resident[k] = struct{}{}
+	s.evictor.add(k, 0)
+	for s.stats.MaxEntries > 0 && len(s.resident) > s.stats.MaxEntries {
+		v, ok := s.evictor.victim()
+		if !ok {
+			return
+		}
+		s.evictor.evicted(v)
+		delete(s.resident, v)
+	}
+}
+
+func (s *policySim) remove(k string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.drop(k)
+}
+
+//无锁
+func (s *policySim) drop(k string) {
+	if _, ok := s.resident[k]; ok {
+		s.evictor.remove(k)
+		delete(s.resident, k)
+	}
+}
+
+func (s *policySim) reset() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.evictor.reset()
+	s.resident = map[string]struct{}{}
+}
+
+//返回各个模拟配置的命中统计
+func (minic *minicache) SimulationReport() []SimulationStats {
+	report := make([]SimulationStats, len(minic.sims))
+	for i, s := range minic.sims {
+		s.mtx.Lock()
+		report[i] = s.stats
+		s.mtx.Unlock()
+	}
+	return report
+}
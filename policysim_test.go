@@ -0,0 +1,40 @@
+package minicache
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPolicySimulationUnbounded(t *testing.T) {
+	c := NewMiniCache(0, 0, WithMaxEntries(2), WithPolicySimulation(EvictLRU, 0), WithPolicySimulation(EvictLRU, 3))
+	defer c.Close()
+	for i := 0; i < 5; i++ {
+		c.Set(fmt.Sprint("k", i), i, 0)
+	}
+	for i := 0; i < 5; i++ {
+		c.Get(fmt.Sprint("k", i))
+	}
+	report := c.SimulationReport()
+	if st := report[0]; st.Hits != 5 || st.Misses != 0 || st.RealHits != 2 || st.RealMisses != 3 {
+		t.Fatalf("unbounded simulation = %+v, want 5 hits and 2 real hits", st)
+	}
+	if st := report[1]; st.Hits != 3 || st.Misses != 2 {
+		t.Fatalf("simulation with 3 entries = %+v, want 3 hits", st)
+	}
+}
+
+func TestPolicySimulationReadPaths(t *testing.T) {
+	c := NewMiniCache(0, 0, WithPolicySimulation(EvictLRU, 0))
+	defer c.Close()
+	c.Set("a", 1, 0)
+	c.GetMultiTouch([]string{"a", "missing"}, 0)
+	c.GetWithMeta("a")
+	c.GetStale("a")
+	c.Memoize("loaded", 0, func() (interface{}, error) { return 1, nil })
+	c.Get("loaded")
+	//Memoize的未命中在加载前后各检查一次
+	st := c.SimulationReport()[0]
+	if st.Hits != 4 || st.RealHits != 4 || st.Misses != 3 || st.RealMisses != 3 {
+		t.Fatalf("SimulationReport() = %+v, want 4 hits and 3 misses", st)
+	}
+}
@@ -0,0 +1,49 @@
+package minicache
+
+import (
+	"strings"
+	"time"
+)
+
+//创建时为键前缀设置默认有效期,见SetPrefixExpiration
+func WithPrefixExpiration(prefix string, d time.Duration) Option {
+	return func(minic *Minicache) {
+		if minic.prefixTTLs == nil {
+			minic.prefixTTLs = map[string]time.Duration{}
+		}
+		minic.prefixTTLs[prefix] = d
+	}
+}
+
+//为键前缀设置默认有效期,以DefaultExpiration写入匹配前缀的键时使用d代替缓存的默认有效期
+//d为NoExpiration时匹配的键默认永不过期,同一键匹配多个前缀时以最长前缀为准
+func (minic *minicache) SetPrefixExpiration(prefix string, d time.Duration) {
+	minic.rwmtx.Lock()
+	defer minic.rwmtx.Unlock()
+	if minic.prefixTTLs == nil {
+		minic.prefixTTLs = map[string]time.Duration{}
+	}
+	minic.prefixTTLs[prefix] = d
+}
+
+//移除键前缀的默认有效期
+func (minic *minicache) ClearPrefixExpiration(prefix string) {
+	minic.rwmtx.Lock()
+	defer minic.rwmtx.Unlock()
+	delete(minic.prefixTTLs, prefix)
+}
+
+//d为DefaultExpiration时替换为匹配的前缀默认有效期,无锁
+func (minic *minicache) prefixTTL(k string, d time.Duration) time.Duration {
+	if d != DefaultExpiration || len(minic.prefixTTLs) == 0 {
+		return d
+	}
+	matched := -1
+	for prefix, pd := range minic.prefixTTLs {
+		if len(prefix) > matched && strings.HasPrefix(k, prefix) {
+			matched = len(prefix)
+			d = pd
+		}
+	}
+	return d
+}
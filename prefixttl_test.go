@@ -0,0 +1,54 @@
+package minicache
+
+import (
+	"testing"
+	"time"
+)
+
+func expiresWithin(t *testing.T, c *Minicache, k string, want time.Duration) {
+	t.Helper()
+	item, found := c.Inspect(k)
+	if !found {
+		t.Fatalf("%s not found", k)
+	}
+	got := time.Until(time.Unix(0, item.Expiration))
+	if got <= want-time.Minute || got > want {
+		t.Fatalf("%s expires in %v, want about %v", k, got, want)
+	}
+}
+
+func TestPrefixExpirationOnRenew(t *testing.T) {
+	c := NewMiniCache(time.Hour, 0, WithPrefixExpiration("session:", 30*time.Minute))
+	defer c.Close()
+	c.Set("session:1", 1, DefaultExpiration)
+	c.Set("other", 1, DefaultExpiration)
+	expiresWithin(t, c, "session:1", 30*time.Minute)
+	expiresWithin(t, c, "other", time.Hour)
+
+	c.Expire("session:1", 5*time.Minute)
+	expiresWithin(t, c, "session:1", 5*time.Minute)
+	c.Touch("session:1")
+	expiresWithin(t, c, "session:1", 30*time.Minute)
+
+	c.Expire("session:1", 5*time.Minute)
+	c.Expire("session:1", DefaultExpiration)
+	expiresWithin(t, c, "session:1", 30*time.Minute)
+
+	c.Expire("session:1", 5*time.Minute)
+	c.GetMultiTouch([]string{"session:1", "other"}, DefaultExpiration)
+	expiresWithin(t, c, "session:1", 30*time.Minute)
+	expiresWithin(t, c, "other", time.Hour)
+
+	c.Expire("session:1", 5*time.Minute)
+	c.Revalidate("session:1", DefaultExpiration)
+	expiresWithin(t, c, "session:1", 30*time.Minute)
+}
+
+func TestOverrideTTLOnTouch(t *testing.T) {
+	c := NewMiniCache(time.Hour, 0)
+	defer c.Close()
+	c.Set("k", 1, DefaultExpiration)
+	c.OverrideTTL("k", 10*time.Minute, time.Now().Add(time.Hour))
+	c.Touch("k")
+	expiresWithin(t, c, "k", 10*time.Minute)
+}
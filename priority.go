@@ -0,0 +1,113 @@
+package minicache
+
+import (
+	"sort"
+	"time"
+)
+
+//按优先级分层的淘汰策略,每个优先级一个独立的基础策略实例,总是先从最低优先级淘汰
+//没有设置优先级的键都在0层,只使用默认优先级时与基础策略完全相同
+type priorityTiers struct {
+	newPolicy func() evictionPolicy
+	tiers     map[int]evictionPolicy
+	levels    []int          //升序排列的优先级
+	priority  map[string]int //非0优先级的键
+}
+
+func newPriorityTiers(newPolicy func() evictionPolicy) *priorityTiers {
+	return &priorityTiers{
+		newPolicy: newPolicy,
+		tiers:     map[int]evictionPolicy{0: newPolicy()},
+		levels:    []int{0},
+		priority:  map[string]int{},
+	}
+}
+
+func (p *priorityTiers) tier(priority int) evictionPolicy {
+	if t, ok := p.tiers[priority]; ok {
+		return t
+	}
+	t := p.newPolicy()
+	p.tiers[priority] = t
+	i := sort.SearchInts(p.levels, priority)
+	p.levels = append(p.levels, 0)
+	copy(p.levels[i+1:], p.levels[i:])
+	p.levels[i] = priority
+	return t
+}
+
+//以优先级priority写入键,优先级变化时移到对应的层
+func (p *priorityTiers) add(k string, priority int) {
+	if old := p.priority[k]; old != priority {
+		p.tiers[old].remove(k)
+	}
+	if priority == 0 {
+		delete(p.priority, k)
+	} else {
+		p.priority[k] = priority
+	}
+	p.tier(priority).add(k)
+}
+
+func (p *priorityTiers) access(k string) {
+	p.tiers[p.priority[k]].access(k)
+}
+
+//基础策略支持读锁下访问时使用
+func (p *priorityTiers) accessShared(k string) {
+	p.tiers[p.priority[k]].(sharedAccessPolicy).accessShared(k)
+}
+
+func (p *priorityTiers) remove(k string) {
+	p.tiers[p.priority[k]].remove(k)
+	delete(p.priority, k)
+}
+
+//基础策略区分淘汰和删除时通知淘汰,否则同remove
+func (p *priorityTiers) evicted(k string) {
+	t := p.tiers[p.priority[k]]
+	if e, ok := t.(evictionAwarePolicy); ok {
+		e.evicted(k)
+	} else {
+		t.remove(k)
+	}
+	delete(p.priority, k)
+}
+
+func (p *priorityTiers) victim() (string, bool) {
+	for _, level := range p.levels {
+		if k, ok := p.tiers[level].victim(); ok {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+func (p *priorityTiers) reset() {
+	zero := p.tiers[0]
+	zero.reset()
+	p.tiers = map[int]evictionPolicy{0: zero}
+	p.levels = []int{0}
+	p.priority = map[string]int{}
+}
+
+//以淘汰优先级写入数据项,容量不足时总是先淘汰优先级低的数据项,同一优先级内按淘汰策略淘汰
+//Set等其他写入方式的优先级为0,重新写入会覆盖之前的优先级
+//写入的键自身所在优先级最低时,容量不足淘汰的就是这个键:写入仍返回nil,键以ReasonEvicted触发OnEvicted并发布EventEvict事件,
+//覆盖已有的键同样如此,此时旧值先以ReasonReplaced通知;需要确认写入是否保留时在OnEvicted或Subscribe中检查
+func (minic *minicache) SetWithPriority(k string, v interface{}, d time.Duration, priority int) error {
+	if minic.latency != nil {
+		defer minic.latency.since(LatencySet, time.Now())
+	}
+	if closed, err := minic.closed(); closed {
+		return err
+	}
+	if over, err := minic.oversize(k, v); over {
+		return err
+	}
+	minic.rwmtx.Lock()
+	defer minic.writeUnlock()
+	item := minic.newItem(k, v, d, SourceSet, minic.sliding)
+	item.priority = priority
+	return minic.store(k, item)
+}
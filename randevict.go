@@ -0,0 +1,51 @@
+package minicache
+
+import "math/rand"
+
+//随机淘汰,不记录访问,适合访问分布均匀的场景
+type randomPolicy struct {
+	keys  []string
+	index map[string]int
+}
+
+func newRandomPolicy() *randomPolicy {
+	return &randomPolicy{index: map[string]int{}}
+}
+
+func (p *randomPolicy) add(k string) {
+	if _, ok := p.index[k]; ok {
+		return
+	}
+	p.index[k] = len(p.keys)
+	p.keys = append(p.keys, k)
+}
+
+func (p *randomPolicy) access(k string) {}
+
+func (p *randomPolicy) accessShared(k string) {}
+
+func (p *randomPolicy) remove(k string) {
+	i, ok := p.index[k]
+	if !ok {
+		return
+	}
+	last := len(p.keys) - 1
+	if i != last {
+		p.keys[i] = p.keys[last]
+		p.index[p.keys[i]] = i
+	}
+	p.keys = p.keys[:last]
+	delete(p.index, k)
+}
+
+func (p *randomPolicy) victim() (string, bool) {
+	if len(p.keys) == 0 {
+		return "", false
+	}
+	return p.keys[rand.Intn(len(p.keys))], true
+}
+
+func (p *randomPolicy) reset() {
+	p.keys = nil
+	p.index = map[string]int{}
+}
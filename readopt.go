@@ -0,0 +1,43 @@
+package minicache
+
+import "sync"
+
+//读优化模式:数据项同时保存在sync.Map中,Get不获取缓存的读写锁
+//适合键集合相对稳定、读远多于写的场景;写操作仍在写锁下进行,并额外更新sync.Map,开销更大
+//Get命中后的滑动过期、淘汰策略记录等功能仍会加各自的锁,需要完全无锁的读取时不应开启这些功能
+func WithReadOptimized() Option {
+	return func(minic *Minicache) {
+		minic.readMap = &sync.Map{}
+	}
+}
+
+//读取数据项,读优化模式和冻结后不加锁
+func (minic *minicache) lookup(k string) (Item, bool) {
+	if minic.Frozen() {
+		item, found := minic.items.get(k)
+		return item, found
+	}
+	if minic.readMap != nil {
+		v, ok := minic.readMap.Load(k)
+		if !ok {
+			return Item{}, false
+		}
+		return v.(Item), true
+	}
+	minic.rwmtx.RLock()
+	item, found := minic.items.get(k)
+	minic.rwmtx.RUnlock()
+	return item, found
+}
+
+//写入数据项,读优化模式下同时更新sync.Map,无锁
+func (minic *minicache) storeItem(k string, item Item) {
+	if minic.Frozen() {
+		return
+	}
+	item.Expiration = minic.items.round(item.Expiration)
+	minic.items.set(k, item)
+	if minic.readMap != nil {
+		minic.readMap.Store(k, item)
+	}
+}
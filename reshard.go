@@ -0,0 +1,233 @@
+package minicache
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+//ShardedCache.Resize的参数,字段为0表示不修改
+type ResizeOptions struct {
+	Shards     int   //新的分片数,向上取整为2的幂
+	MaxEntries int   //整个缓存的数据项数量上限,平均分配到各分片,小于0表示不限制
+	MaxCost    int64 //整个缓存的开销上限,平均分配到各分片,小于0表示不限制
+}
+
+//改变分片数时把旧分片中的数据项迁到新分片的后台任务
+type migration struct {
+	done chan struct{} //迁移完成或中止时关闭
+	stop chan struct{}
+}
+
+//等待迁移结束,m为nil时直接返回
+func (m *migration) wait() {
+	if m != nil {
+		<-m.done
+	}
+}
+
+//中止迁移,只调用一次
+func (m *migration) abort() {
+	if m != nil {
+		close(m.stop)
+	}
+}
+
+func (m *migration) finished() bool {
+	if m == nil {
+		return true
+	}
+	select {
+	case <-m.done:
+		return true
+	default:
+		return false
+	}
+}
+
+//在线调整分片数和容量上限,不清空缓存,返回的channel在调整完成后关闭
+//只调整容量时在返回前按新上限淘汰,返回的channel已关闭
+//改变分片数时后台逐个旧分片迁移:等该分片上进行中的操作结束后,持有它的写锁把数据项移到新分片,
+//还没有迁移的分片照常读写,正在迁移的分片上的操作等待迁移完成(通常为毫秒级);全部迁移后关闭旧分片
+//上一次迁移还没有完成时返回ErrResizing,冻结后返回ErrReadOnly,Load和Freeze等待迁移完成
+func (sc *ShardedCache) Resize(opts ResizeOptions) (<-chan struct{}, error) {
+	sc.resizeMtx.Lock()
+	defer sc.resizeMtx.Unlock()
+	if sc.closed {
+		return nil, ErrCacheClosed
+	}
+	if !sc.migration.finished() {
+		return nil, ErrResizing
+	}
+	from := sc.tables.cur.Load()
+	if from.shards[0].Frozen() {
+		return nil, ErrReadOnly
+	}
+	if opts.MaxEntries != 0 {
+		sc.maxEntries = max(opts.MaxEntries, 0)
+	}
+	if opts.MaxCost != 0 {
+		sc.maxCost = max(opts.MaxCost, 0)
+	}
+	n := len(from.shards)
+	if opts.Shards > 0 {
+		n = shardCount(opts.Shards)
+	}
+	done := make(chan struct{})
+	if n == len(from.shards) {
+		if opts.MaxEntries != 0 || opts.MaxCost != 0 {
+			for _, s := range from.shards {
+				sc.bound(s, n)
+			}
+		}
+		close(done)
+		return done, nil
+	}
+	to := newShardTable(n)
+	for i := range to.shards {
+		s := NewMiniCache(sc.defaultExp, 0, sc.opts...)
+		sc.bound(s, n)
+		if sc.onExpired != nil {
+			s.OnExpired(sc.onExpired)
+		}
+		if sc.onEvicted != nil {
+			s.OnEvicted(sc.onEvicted)
+		}
+		for _, fn := range sc.settings {
+			fn(s)
+		}
+		to.shards[i] = s
+	}
+	from.moved = make([]chan struct{}, len(from.shards))
+	for i := range from.moved {
+		from.moved[i] = make(chan struct{})
+	}
+	m := &migration{done: done, stop: make(chan struct{})}
+	sc.migration = m
+	//先登记旧表,看到新表的操作一定能看到它
+	sc.tables.old.Store(from)
+	sc.tables.cur.Store(to)
+	go sc.migrate(from, to, m)
+	return done, nil
+}
+
+//按整个缓存的上限设置分片s的上限,分片数为n
+func (sc *ShardedCache) bound(s *Minicache, n int) {
+	s.Resize((sc.maxEntries + n - 1) / n)
+	s.ResizeCost((sc.maxCost + int64(n) - 1) / int64(n))
+}
+
+//逐个封住并迁移旧分片,全部完成后关闭旧分片
+func (sc *ShardedCache) migrate(from, to *shardTable, m *migration) {
+	defer close(m.done)
+	for i, s := range from.shards {
+		if !from.seal(i, m.stop) {
+			return
+		}
+		dsts := sc.moveShard(s, to)
+		close(from.moved[i])
+		//restore在新分片上淘汰的数据项,回调在等待的操作继续之后执行
+		for _, dst := range dsts {
+			dst.rwmtx.Lock()
+			dst.unlock()
+		}
+	}
+	sc.tables.old.Store(nil)
+	for _, s := range from.shards {
+		s.Close()
+	}
+}
+
+//等到分片i上没有进行中的操作时封住它,中止时返回false
+//分片上一直有操作时持续等待,不阻止新的操作进入,避免回调中对同一分片的操作互相等待
+func (t *shardTable) seal(i int, stop <-chan struct{}) bool {
+	for !atomic.CompareAndSwapInt64(&t.inflight[i].n, 0, sealed) {
+		select {
+		case <-stop:
+			return false
+		case <-time.After(50 * time.Microsecond):
+		}
+	}
+	return true
+}
+
+//迁移中的数据项及其固定和context绑定
+type movedItem struct {
+	item   Item
+	found  bool //只有固定时为false
+	pinned bool
+	ctx    context.Context
+}
+
+//持有s的写锁把它的数据项按键移到to的各分片,固定的键和SetScoped的绑定随之迁移,返回写入过的分片
+//淘汰回调留给调用方在分片迁移完成后触发;to已满时按to的淘汰策略处理,拒绝模式下丢弃迁移的数据项
+func (sc *ShardedCache) moveShard(s *Minicache, to *shardTable) []*Minicache {
+	s.rwmtx.Lock()
+	defer s.rwmtx.Unlock()
+	parts := map[*Minicache]map[string]movedItem{}
+	add := func(k string, m movedItem) {
+		dst := to.shard(sc.hasher(k))
+		if parts[dst] == nil {
+			parts[dst] = map[string]movedItem{}
+		}
+		parts[dst][k] = m
+	}
+	//固定针对键,没有数据项的固定同样迁移
+	for k := range s.pins {
+		add(k, movedItem{pinned: true})
+	}
+	s.items.each(func(k string, _ Item) bool {
+		m := movedItem{found: true, pinned: s.pinned(k)}
+		if scope, ok := s.scopes[k]; ok {
+			m.ctx = scope.ctx
+		}
+		m.item, _ = s.take(k)
+		add(k, m)
+		return true
+	})
+	dsts := make([]*Minicache, 0, len(parts))
+	for dst, items := range parts {
+		dst.rwmtx.Lock()
+		for k, m := range items {
+			if m.pinned {
+				if dst.pins == nil {
+					dst.pins = map[string]struct{}{}
+				}
+				dst.pins[k] = struct{}{}
+			}
+			if m.found && dst.restore(k, m.item) == nil && m.ctx != nil {
+				dst.bind(m.ctx, k)
+			}
+		}
+		dst.rwmtx.Unlock()
+		dsts = append(dsts, dst)
+	}
+	return dsts
+}
+
+//移除k并返回数据项,不触发回调和事件,不写入AOF,用于把数据项移到其他分片,无锁
+func (minic *minicache) take(k string) (Item, bool) {
+	item, found := minic.items.get(k)
+	if !found {
+		return Item{}, false
+	}
+	minic.items.remove(k)
+	if minic.readMap != nil {
+		minic.readMap.Delete(k)
+	}
+	minic.totalCost -= item.cost
+	for _, sim := range minic.sims {
+		sim.remove(k)
+	}
+	if minic.dedup != nil {
+		minic.dedup.release(item.shared)
+	}
+	if ev := minic.evictor.Load(); ev != nil {
+		ev.remove(k)
+	}
+	minic.unscope(k)
+	if minic.nsMetrics != nil {
+		minic.nsMetrics.entry(k, -1, -minic.sizedCost(item))
+	}
+	return item, true
+}
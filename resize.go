@@ -0,0 +1,62 @@
+package minicache
+
+//运行时调整数据项数量上限,缩小时立即按淘汰策略淘汰多出的数据项,n不大于0表示不限制,冻结后不起作用
+//淘汰策略内部按容量确定的参数(频率衰减周期、ARC队列长度等)保持创建时的值
+func (minic *minicache) Resize(n int) {
+	minic.rwmtx.Lock()
+	defer minic.unlock()
+	if minic.Frozen() {
+		return
+	}
+	minic.maxEntries = n
+	minic.applyBounds()
+}
+
+//运行时调整开销上限,缩小时立即按淘汰策略淘汰,total不大于0表示不限制,冻结后不起作用
+func (minic *minicache) ResizeCost(total int64) {
+	minic.rwmtx.Lock()
+	defer minic.unlock()
+	if minic.Frozen() {
+		return
+	}
+	minic.maxCost = total
+	minic.applyBounds()
+}
+
+//按当前上限创建淘汰策略和门卫过滤器,无锁
+func (minic *minicache) initBounds() {
+	if minic.maxEntries <= 0 && minic.maxCost <= 0 {
+		return
+	}
+	if minic.evictor.Load() == nil && minic.evictionPolicy != EvictReject {
+		minic.evictor.Store(newEvictor(minic.evictionPolicy, minic.maxEntries, minic.admission))
+	}
+	if minic.doorkeeper == nil && minic.doorkeeperOn {
+		minic.doorkeeper = newDoorkeeper(max(minic.maxEntries, defaultPolicyCapacity), minic.doorkeeperWindow)
+	}
+}
+
+//上限改变后补建淘汰策略并淘汰超出的数据项,拒绝模式下只拒绝之后的写入,无锁
+func (minic *minicache) applyBounds() {
+	ev := minic.evictor.Load()
+	if ev == nil {
+		minic.initBounds()
+		if ev = minic.evictor.Load(); ev == nil {
+			return
+		}
+		//已有的数据项按写入的优先级登记,先后顺序不代表访问顺序
+		minic.items.each(func(k string, item Item) bool {
+			if !minic.pinned(k) {
+				ev.add(k, item.priority)
+			}
+			return true
+		})
+	}
+	for minic.overCapacity() {
+		k, ok := ev.victim()
+		if !ok {
+			return
+		}
+		minic.delete(k, EventEvict)
+	}
+}
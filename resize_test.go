@@ -0,0 +1,61 @@
+package minicache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestResizeShrinks(t *testing.T) {
+	c := NewMiniCache(0, 0, WithMaxEntries(100))
+	defer c.Close()
+	for i := 0; i < 100; i++ {
+		c.Set(fmt.Sprint(i), i, 0)
+	}
+	c.Resize(10)
+	if n := c.Count(); n != 10 {
+		t.Fatalf("Count() = %d, want 10", n)
+	}
+	c.Set("new", 1, 0)
+	if n := c.Count(); n != 10 {
+		t.Fatalf("Count() after Set = %d, want 10", n)
+	}
+}
+
+//无上限的缓存在Resize时才创建淘汰策略,与并发的Get之间不能有数据竞争
+func TestResizeUnbounded(t *testing.T) {
+	for round := 0; round < 10; round++ {
+		c := NewMiniCache(0, 0)
+		for i := 0; i < 100; i++ {
+			c.Set(fmt.Sprint(i), i, 0)
+		}
+		var wg, started sync.WaitGroup
+		stop := make(chan struct{})
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			started.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; ; i++ {
+					if i == 100 {
+						started.Done()
+					}
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					c.Get(fmt.Sprint(i % 100))
+				}
+			}()
+		}
+		started.Wait()
+		c.Resize(20)
+		close(stop)
+		wg.Wait()
+		if n := c.Count(); n != 20 {
+			t.Fatalf("Count() = %d, want 20", n)
+		}
+		c.Close()
+	}
+}
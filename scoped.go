@@ -0,0 +1,52 @@
+package minicache
+
+import "context"
+
+//与context绑定的数据项
+type scope struct {
+	ctx  context.Context
+	stop func() bool
+}
+
+//设置数据项,ctx取消时删除该数据项,有效期使用默认有效期
+//取消监听通过context.AfterFunc挂在ctx自身的取消链上,不为每个键启动goroutine
+//该键被再次写入或删除后解除绑定
+func (minic *minicache) SetScoped(ctx context.Context, k string, v interface{}) error {
+	if closed, err := minic.closed(); closed {
+		return err
+	}
+	if over, err := minic.oversize(k, v); over {
+		return err
+	}
+	minic.rwmtx.Lock()
+	defer minic.writeUnlock()
+	if err := minic.set(k, v, DefaultExpiration); err != nil {
+		return err
+	}
+	minic.bind(ctx, k)
+	return nil
+}
+
+//绑定k与ctx,ctx取消时删除k,无锁
+func (minic *minicache) bind(ctx context.Context, k string) {
+	sc := &scope{ctx: ctx}
+	sc.stop = context.AfterFunc(ctx, func() {
+		minic.rwmtx.Lock()
+		defer minic.unlock()
+		if minic.scopes[k] == sc && !minic.Frozen() {
+			minic.delete(k, EventDelete)
+		}
+	})
+	if minic.scopes == nil {
+		minic.scopes = map[string]*scope{}
+	}
+	minic.scopes[k] = sc
+}
+
+//解除键与context的绑定,无锁
+func (minic *minicache) unscope(k string) {
+	if sc, ok := minic.scopes[k]; ok {
+		sc.stop()
+		delete(minic.scopes, k)
+	}
+}
@@ -0,0 +1,97 @@
+package minicache
+
+import (
+	"math/rand"
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+//影子读对比结果
+type ShadowReport struct {
+	Samples       uint64 //抽样读次数
+	PrimaryHits   uint64 //抽样中主缓存命中次数
+	CandidateHits uint64 //抽样中候选缓存命中次数
+	Mismatches    uint64 //两边都命中但值不同的次数
+}
+
+//主缓存抽样命中率
+func (r ShadowReport) PrimaryHitRate() float64 {
+	if r.Samples == 0 {
+		return 0
+	}
+	return float64(r.PrimaryHits) / float64(r.Samples)
+}
+
+//候选缓存抽样命中率
+func (r ShadowReport) CandidateHitRate() float64 {
+	if r.Samples == 0 {
+		return 0
+	}
+	return float64(r.CandidateHits) / float64(r.Samples)
+}
+
+//影子缓存,读请求由主缓存响应,按比例抽样同时读取候选缓存并对比结果
+//写入同时作用于两边,候选缓存可以使用不同的有效期等配置
+type Shadow struct {
+	primary   *Minicache
+	candidate *Minicache
+	fraction  float64
+
+	samples       uint64
+	primaryHits   uint64
+	candidateHits uint64
+	mismatches    uint64
+}
+
+//创建影子缓存,fraction为抽样对比的读请求比例
+func NewShadow(primary, candidate *Minicache, fraction float64) *Shadow {
+	return &Shadow{
+		primary:   primary,
+		candidate: candidate,
+		fraction:  fraction,
+	}
+}
+
+//从主缓存读取,抽样时同时读取候选缓存
+func (s *Shadow) Get(k string) (interface{}, bool) {
+	v, found := s.primary.Get(k)
+	if s.fraction <= 0 || rand.Float64() >= s.fraction {
+		return v, found
+	}
+	cv, cfound := s.candidate.Get(k)
+	atomic.AddUint64(&s.samples, 1)
+	if found {
+		atomic.AddUint64(&s.primaryHits, 1)
+	}
+	if cfound {
+		atomic.AddUint64(&s.candidateHits, 1)
+	}
+	if found && cfound && !reflect.DeepEqual(v, cv) {
+		atomic.AddUint64(&s.mismatches, 1)
+	}
+	return v, found
+}
+
+//同时写入主缓存和候选缓存,返回主缓存的错误,候选缓存的错误只影响对比结果
+func (s *Shadow) Set(k string, v interface{}, d time.Duration) error {
+	err := s.primary.Set(k, v, d)
+	s.candidate.Set(k, v, d)
+	return err
+}
+
+//同时从主缓存和候选缓存删除
+func (s *Shadow) Delete(k string) {
+	s.primary.Delete(k)
+	s.candidate.Delete(k)
+}
+
+//返回对比统计
+func (s *Shadow) Report() ShadowReport {
+	return ShadowReport{
+		Samples:       atomic.LoadUint64(&s.samples),
+		PrimaryHits:   atomic.LoadUint64(&s.primaryHits),
+		CandidateHits: atomic.LoadUint64(&s.candidateHits),
+		Mismatches:    atomic.LoadUint64(&s.mismatches),
+	}
+}
@@ -0,0 +1,48 @@
+package minicache
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestShadowSetReturnsPrimaryError(t *testing.T) {
+	primary := NewMiniCache(0, 0, WithMaxEntries(1), WithEvictionPolicy(EvictReject))
+	candidate := NewMiniCache(0, 0)
+	defer primary.Close()
+	defer candidate.Close()
+	s := NewShadow(primary, candidate, 1)
+	if err := s.Set("a", 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("b", 2, 0); !errors.Is(err, ErrCacheFull) {
+		t.Fatalf("Set error = %v, want %v", err, ErrCacheFull)
+	}
+	if _, found := candidate.Get("b"); !found {
+		t.Fatal("candidate missed the write")
+	}
+	candidate.Close()
+	if err := s.Set("a", 3, 0); err != nil {
+		t.Fatalf("candidate error leaked: %v", err)
+	}
+	primary.Close()
+	if err := s.Set("a", 4, 0); !errors.Is(err, ErrCacheClosed) {
+		t.Fatalf("Set error = %v, want %v", err, ErrCacheClosed)
+	}
+}
+
+func TestShadowReport(t *testing.T) {
+	primary := NewMiniCache(0, 0)
+	candidate := NewMiniCache(0, 0)
+	defer primary.Close()
+	defer candidate.Close()
+	s := NewShadow(primary, candidate, 1)
+	s.Set("a", 1, 0)
+	candidate.Set("a", 2, 0)
+	primary.Set("b", 1, 0)
+	s.Get("a")
+	s.Get("b")
+	r := s.Report()
+	if r.Samples != 2 || r.PrimaryHits != 2 || r.CandidateHits != 1 || r.Mismatches != 1 {
+		t.Fatalf("Report() = %+v", r)
+	}
+}
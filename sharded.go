@@ -0,0 +1,565 @@
+package minicache
+
+import (
+	"errors"
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//默认分片数
+const defaultShards = 256
+
+//分片已封住等待迁移,不再接受新的操作
+const sealed = -1 << 62
+
+//按键的哈希分片的缓存,每个分片是一个独立加锁的Minicache,不同分片上的读写互不阻塞
+//实现Cache,单个键的操作与Minicache相同;Count、Flush和统计等跨分片操作逐个分片执行并汇总,不是原子的
+//RegisterLoader、SetPrefixExpiration等配置作用于所有分片,订阅和回调收到所有分片的事件
+type ShardedCache struct {
+	tables     *shardTables
+	shards     int
+	opts       []Option
+	hasher     func(string) uint64
+	defaultExp time.Duration
+	maxEntries int   //所有分片的数据项数量上限之和,0表示不限制
+	maxCost    int64 //所有分片的开销上限之和,0表示不限制
+	onExpired  func(k string, v interface{})
+	onEvicted  func(k string, v interface{}, reason EvictionReason)
+	keyLocks   keyLocks
+	events     *eventHub            //所有分片共用
+	settings   []func(s *Minicache) //RegisterLoader等配置操作,在Resize新建的分片上重放
+	resizeMtx  sync.Mutex
+	migration  *migration //最近一次改变分片数的迁移
+	closed     bool
+	closeHooks closeHooks
+	done       chan struct{} //Close完成后关闭
+	gcWorkers  int
+	gcInterval time.Duration
+	gcStats    *gcStatsRecorder //每轮清理所有分片的汇总统计,gc goroutine也持有
+	gcRunning  *sync.WaitGroup  //后台gc goroutine,Shutdown等待其退出
+	stopGc     chan bool
+	stopOnce   sync.Once
+}
+
+//一组分片,分片数是2的幂
+type shardTable struct {
+	shards   []*Minicache
+	mask     uint64
+	inflight []shardCounter  //每个分片上进入还未结束的单键操作数,开始迁移该分片时置为sealed
+	moved    []chan struct{} //改变分片数时创建,对应的分片迁移完成后关闭
+}
+
+type shardCounter struct {
+	n int64
+	_ [56]byte //独占缓存行
+}
+
+func newShardTable(n int) *shardTable {
+	return &shardTable{shards: make([]*Minicache, n), mask: uint64(n - 1), inflight: make([]shardCounter, n)}
+}
+
+//当前分片表和正在迁出的旧分片表,不引用ShardedCache,gc goroutine只持有它
+type shardTables struct {
+	cur atomic.Pointer[shardTable]
+	old atomic.Pointer[shardTable] //没有迁移时为nil
+}
+
+//分片缓存的配置
+type ShardOption func(*ShardedCache)
+
+//设置分片数,向上取整为2的幂,n不大于0时使用默认的256
+func WithShards(n int) ShardOption {
+	return func(sc *ShardedCache) {
+		if n > 0 {
+			sc.shards = shardCount(n)
+		}
+	}
+}
+
+//向上取整为2的幂
+func shardCount(n int) int {
+	size := 1
+	for size < n {
+		size <<= 1
+	}
+	return size
+}
+
+//创建每个分片时使用的Option,WithMaxEntries等容量限制按分片计算,乘以分片数作为整个缓存的上限
+//WithPersistence、WithAutoSave和WithAOF会让所有分片写同一个文件,在分片上被忽略,持久化使用ShardedCache.SaveToFile
+func WithShardOptions(opts ...Option) ShardOption {
+	return func(sc *ShardedCache) {
+		sc.opts = append(sc.opts, opts...)
+	}
+}
+
+//设置选择分片的哈希函数,默认FNV-1a;键有很长的公共前缀时应选择对整个键分布均匀的函数
+func WithHasher(fn func(string) uint64) ShardOption {
+	return func(sc *ShardedCache) {
+		sc.hasher = fn
+	}
+}
+
+//过期清理时同时清理的分片数,默认GOMAXPROCS
+func WithGCParallelism(n int) ShardOption {
+	return func(sc *ShardedCache) {
+		sc.gcWorkers = n
+	}
+}
+
+//创建分片缓存,所有分片共用一个后台gc goroutine,gcInterval小于等于0时不启动,过期数据项在访问时删除
+func NewShardedCache(defaultExpiration, gcInterval time.Duration, opts ...ShardOption) *ShardedCache {
+	sc := &ShardedCache{
+		tables:     &shardTables{},
+		defaultExp: defaultExpiration,
+		events:     &eventHub{},
+		done:       make(chan struct{}),
+		gcInterval: gcInterval,
+		gcStats:    &gcStatsRecorder{},
+		gcRunning:  &sync.WaitGroup{},
+		stopGc:     make(chan bool),
+	}
+	for _, opt := range opts {
+		opt(sc)
+	}
+	if sc.shards == 0 {
+		sc.shards = defaultShards
+	}
+	if sc.gcWorkers <= 0 {
+		sc.gcWorkers = runtime.GOMAXPROCS(0)
+	}
+	if sc.hasher == nil {
+		sc.hasher = fnv64a
+	}
+	sc.opts = append(sc.opts, withoutFiles(), withEventHub(sc.events))
+	probe := &Minicache{&minicache{}}
+	for _, opt := range sc.opts {
+		opt(probe)
+	}
+	sc.maxEntries = probe.maxEntries * sc.shards
+	sc.maxCost = probe.maxCost * int64(sc.shards)
+	t := newShardTable(sc.shards)
+	for i := range t.shards {
+		t.shards[i] = NewMiniCache(defaultExpiration, 0, sc.opts...)
+	}
+	sc.tables.cur.Store(t)
+	if gcInterval > 0 {
+		sc.gcRunning.Add(1)
+		go shardGcLoop(sc.tables, sc.gcWorkers, gcInterval, sc.gcStats, sc.gcRunning, sc.stopGc)
+	}
+	//gc goroutine不引用sc,sc不再被引用时由终结器关闭
+	runtime.SetFinalizer(sc, func(sc *ShardedCache) {
+		sc.Close()
+	})
+	return sc
+}
+
+//忽略按文件持久化的选项,放在分片选项的最后
+func withoutFiles() Option {
+	return func(minic *Minicache) {
+		minic.persistFile = ""
+		minic.autoSaveInterval = 0
+		minic.onAutoSaveError = nil
+		minic.aofFile = ""
+	}
+}
+
+//FNV-1a
+func fnv64a(k string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(k); i++ {
+		h ^= uint64(k[i])
+		h *= 1099511628211
+	}
+	return h
+}
+
+func (t *shardTable) shard(h uint64) *Minicache {
+	return t.shards[h&t.mask]
+}
+
+//在分片i上登记一个操作,分片已封住时返回false
+func (t *shardTable) tryEnter(i uint64) (*int64, bool) {
+	n := &t.inflight[i].n
+	if atomic.AddInt64(n, 1) < 0 {
+		atomic.AddInt64(n, -1)
+		return nil, false
+	}
+	return n, true
+}
+
+//登记一个对哈希为h的键的操作,返回执行操作的分片和操作结束后用exit减一的计数
+//改变分片数期间,键所在的旧分片还没有迁移时仍在旧分片上执行,正在迁移时等待该分片迁移完成
+func (ts *shardTables) enter(h uint64) (*Minicache, *int64) {
+	for {
+		t := ts.cur.Load()
+		if old := ts.old.Load(); old != nil && old != t {
+			j := h & old.mask
+			if n, ok := old.tryEnter(j); ok {
+				return old.shards[j], n
+			}
+			<-old.moved[j]
+		}
+		i := h & t.mask
+		if n, ok := t.tryEnter(i); ok {
+			//登记前分片表已被替换时撤销,替换之后旧表的计数只减不增
+			if ts.cur.Load() == t {
+				return t.shards[i], n
+			}
+			atomic.AddInt64(n, -1)
+		}
+	}
+}
+
+//当前分片表和正在迁出的旧表,没有迁移时old为nil
+//先读cur再读old,与Resize先写old再写cur的顺序配合,不会漏掉旧表
+func (ts *shardTables) load() (old, cur *shardTable) {
+	cur = ts.cur.Load()
+	if old = ts.old.Load(); old == cur {
+		old = nil
+	}
+	return old, cur
+}
+
+//旧表在前,迁移时数据项只从旧表移到新表,先旧后新地访问不会漏掉正在迁移的数据项
+func (ts *shardTables) all() []*Minicache {
+	old, cur := ts.load()
+	if old == nil {
+		return cur.shards
+	}
+	return append(append(make([]*Minicache, 0, len(old.shards)+len(cur.shards)), old.shards...), cur.shards...)
+}
+
+//键所在的分片,返回的计数在操作结束后用exit减一
+func (sc *ShardedCache) enter(k string) (*Minicache, *int64) {
+	return sc.tables.enter(sc.hasher(k))
+}
+
+func exit(n *int64) {
+	atomic.AddInt64(n, -1)
+}
+
+func shardGcLoop(tables *shardTables, workers int, interval time.Duration, stats *gcStatsRecorder, running *sync.WaitGroup, stop chan bool) {
+	defer running.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			stats.record(deleteExpiredShards(tables.all(), workers, nil))
+		case <-stop:
+			return
+		}
+	}
+}
+
+//停止后台gc
+func (sc *ShardedCache) Stopgc() {
+	if sc.gcInterval <= 0 {
+		return
+	}
+	sc.stopOnce.Do(func() {
+		close(sc.stopGc)
+	})
+}
+
+//分片数
+func (sc *ShardedCache) Shards() int {
+	return len(sc.tables.cur.Load().shards)
+}
+
+func (sc *ShardedCache) Set(k string, v interface{}, d time.Duration) error {
+	s, n := sc.enter(k)
+	defer exit(n)
+	return s.Set(k, v, d)
+}
+
+func (sc *ShardedCache) SetForever(k string, v interface{}) error {
+	s, n := sc.enter(k)
+	defer exit(n)
+	return s.SetForever(k, v)
+}
+
+func (sc *ShardedCache) SetWithExpireAt(k string, v interface{}, t time.Time) error {
+	s, n := sc.enter(k)
+	defer exit(n)
+	return s.SetWithExpireAt(k, v, t)
+}
+
+func (sc *ShardedCache) SetSliding(k string, v interface{}, d time.Duration) error {
+	s, n := sc.enter(k)
+	defer exit(n)
+	return s.SetSliding(k, v, d)
+}
+
+func (sc *ShardedCache) Add(k string, v interface{}, d time.Duration) error {
+	s, n := sc.enter(k)
+	defer exit(n)
+	return s.Add(k, v, d)
+}
+
+func (sc *ShardedCache) AddOrGet(k string, v interface{}, d time.Duration) (interface{}, error) {
+	s, n := sc.enter(k)
+	defer exit(n)
+	return s.AddOrGet(k, v, d)
+}
+
+func (sc *ShardedCache) Replace(k string, v interface{}, d time.Duration) error {
+	s, n := sc.enter(k)
+	defer exit(n)
+	return s.Replace(k, v, d)
+}
+
+func (sc *ShardedCache) Upsert(k string, v interface{}, d time.Duration, merge func(existing, new interface{}) interface{}) error {
+	s, n := sc.enter(k)
+	defer exit(n)
+	return s.Upsert(k, v, d, merge)
+}
+
+func (sc *ShardedCache) Get(k string) (interface{}, bool) {
+	s, n := sc.enter(k)
+	defer exit(n)
+	return s.Get(k)
+}
+
+func (sc *ShardedCache) GetStale(k string) (v interface{}, expired bool, found bool) {
+	s, n := sc.enter(k)
+	defer exit(n)
+	return s.GetStale(k)
+}
+
+func (sc *ShardedCache) Inspect(k string) (Item, bool) {
+	s, n := sc.enter(k)
+	defer exit(n)
+	return s.Inspect(k)
+}
+
+func (sc *ShardedCache) TTL(k string) (time.Duration, bool) {
+	s, n := sc.enter(k)
+	defer exit(n)
+	return s.TTL(k)
+}
+
+func (sc *ShardedCache) Touch(k string) bool {
+	s, n := sc.enter(k)
+	defer exit(n)
+	return s.Touch(k)
+}
+
+func (sc *ShardedCache) Expire(k string, d time.Duration) bool {
+	s, n := sc.enter(k)
+	defer exit(n)
+	return s.Expire(k, d)
+}
+
+func (sc *ShardedCache) Persist(k string) bool {
+	s, n := sc.enter(k)
+	defer exit(n)
+	return s.Persist(k)
+}
+
+//同Minicache.WithLock,键锁由所有分片共用,改变分片数前后同一个键上的WithLock也依次执行
+func (sc *ShardedCache) WithLock(k string, fn func(v interface{}, found bool) (interface{}, bool)) error {
+	mtx := sc.keyLocks.of(k)
+	mtx.Lock()
+	defer mtx.Unlock()
+	s, n := sc.enter(k)
+	defer exit(n)
+	return s.WithLock(k, fn)
+}
+
+func (sc *ShardedCache) Delete(k string) {
+	s, n := sc.enter(k)
+	defer exit(n)
+	s.Delete(k)
+}
+
+//所有分片的数据项数量之和
+func (sc *ShardedCache) Count() int {
+	n := 0
+	for _, s := range sc.tables.all() {
+		n += s.Count()
+	}
+	return n
+}
+
+//逐个分片清空
+func (sc *ShardedCache) Flush() {
+	for _, s := range sc.tables.all() {
+		s.Flush()
+	}
+}
+
+//并行删除各分片的过期数据项,同时清理的分片数不超过WithGCParallelism的设置,返回删除的总数
+func (sc *ShardedCache) DeleteExpired() int {
+	return sc.DeleteExpiredFunc(nil)
+}
+
+//同DeleteExpired,并对每个被删除的数据项调用fn,不同分片上的fn可能并发调用
+func (sc *ShardedCache) DeleteExpiredFunc(fn func(k string, v interface{})) int {
+	stats := deleteExpiredShards(sc.tables.all(), sc.gcWorkers, fn)
+	sc.gcStats.record(stats)
+	return stats.Deleted
+}
+
+//并行清理各分片,返回本轮所有分片的汇总统计
+func deleteExpiredShards(shards []*Minicache, workers int, fn func(k string, v interface{})) GCStats {
+	var (
+		wg   sync.WaitGroup
+		mtx  sync.Mutex
+		next int64 = -1
+	)
+	total := GCStats{Start: time.Now()}
+	for w := 0; w < min(workers, len(shards)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := atomic.AddInt64(&next, 1); i < int64(len(shards)); i = atomic.AddInt64(&next, 1) {
+				stats := shards[i].deleteExpired(fn)
+				mtx.Lock()
+				total.add(stats)
+				mtx.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	total.Duration = time.Since(total.Start)
+	return total
+}
+
+//注册过期回调,所有分片共用,Resize新建的分片同样使用
+func (sc *ShardedCache) OnExpired(fn func(k string, v interface{})) {
+	sc.resizeMtx.Lock()
+	defer sc.resizeMtx.Unlock()
+	sc.onExpired = fn
+	for _, s := range sc.tables.all() {
+		s.OnExpired(fn)
+	}
+}
+
+//注册离开缓存的回调,所有分片共用,Resize新建的分片同样使用
+func (sc *ShardedCache) OnEvicted(fn func(k string, v interface{}, reason EvictionReason)) {
+	sc.resizeMtx.Lock()
+	defer sc.resizeMtx.Unlock()
+	sc.onEvicted = fn
+	for _, s := range sc.tables.all() {
+		s.OnEvicted(fn)
+	}
+}
+
+//汇总所有分片的容量与淘汰统计,上限为当前各分片上限之和
+func (sc *ShardedCache) Stats() Stats {
+	total := Stats{Removals: make(map[EvictionReason]uint64, reasonCount)}
+	for _, s := range sc.tables.all() {
+		st := s.Stats()
+		total.Entries += st.Entries
+		total.Cost += st.Cost
+		total.Bytes += st.Bytes
+		total.AdmissionRejected += st.AdmissionRejected
+		total.DoorkeeperSkipped += st.DoorkeeperSkipped
+		for reason, n := range st.Removals {
+			total.Removals[reason] += n
+		}
+	}
+	for _, s := range sc.tables.cur.Load().shards {
+		st := s.Stats()
+		total.MaxEntries += st.MaxEntries
+		total.MaxCost += st.MaxCost
+	}
+	return total
+}
+
+//所有分片合并写入一个快照,格式与Minicache.Save相同,压缩和加密使用分片选项中的配置
+//逐个分片复制数据项,不是整个缓存在同一时刻的快照
+func (sc *ShardedCache) Save(w io.Writer) error {
+	items := map[string]Item{}
+	for _, s := range sc.tables.all() {
+		for k, v := range s.snapshot() {
+			items[k] = v
+		}
+	}
+	return sc.tables.cur.Load().shards[0].encode(w, items)
+}
+
+//序列化到文件,与Minicache.SaveToFile一样先写临时文件再原子重命名
+func (sc *ShardedCache) SaveToFile(fileName string) error {
+	return writeFileAtomic(fileName, sc.Save)
+}
+
+//读取快照并按键分配到各分片,也可以读取Minicache.Save写入的快照,分片数可以与保存时不同
+//正在改变分片数时先等待迁移完成
+func (sc *ShardedCache) Load(r io.Reader) error {
+	sc.resizeMtx.Lock()
+	defer sc.resizeMtx.Unlock()
+	sc.migration.wait()
+	t := sc.tables.cur.Load()
+	first := t.shards[0]
+	if closed, err := first.closed(); closed {
+		return err
+	}
+	items, err := decodeSnapshot(r, first.snapKeys)
+	if err != nil {
+		return err
+	}
+	parts := make([]map[string]Item, len(t.shards))
+	for k, v := range items {
+		i := sc.hasher(k) & t.mask
+		if parts[i] == nil {
+			parts[i] = map[string]Item{}
+		}
+		parts[i][k] = v
+	}
+	for i, part := range parts {
+		if part != nil {
+			t.shards[i].merge(part)
+		}
+	}
+	return nil
+}
+
+//从文件中读取
+func (sc *ShardedCache) LoadFromFile(fileName string) error {
+	return readFile(fileName, sc.Load)
+}
+
+//冻结所有分片,之后写操作返回ErrReadOnly;正在改变分片数时先等待迁移完成
+func (sc *ShardedCache) Freeze() {
+	sc.resizeMtx.Lock()
+	defer sc.resizeMtx.Unlock()
+	sc.migration.wait()
+	sc.Stopgc()
+	for _, s := range sc.tables.all() {
+		s.Freeze()
+	}
+}
+
+//停止gc和未完成的迁移,关闭所有分片后按注册的相反顺序执行OnClose注册的函数
+//可重复调用,只有第一次调用返回各分片和关闭函数的错误,之后的调用等待第一次调用完成
+func (sc *ShardedCache) Close() error {
+	sc.resizeMtx.Lock()
+	first := !sc.closed
+	if first {
+		sc.closed = true
+		sc.migration.abort()
+	}
+	m := sc.migration
+	sc.resizeMtx.Unlock()
+	if !first {
+		<-sc.done
+		return nil
+	}
+	m.wait()
+	sc.Stopgc()
+	var errs []error
+	for _, s := range sc.tables.all() {
+		if err := s.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	errs = append(errs, sc.closeHooks.run())
+	close(sc.done)
+	return errors.Join(errs...)
+}
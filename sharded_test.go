@@ -0,0 +1,402 @@
+package minicache
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShardedSaveLoad(t *testing.T) {
+	key := make([]byte, 32)
+	opts := WithShardOptions(WithSnapshotCompression(CompressionGzip, 0), WithSnapshotEncryption(key))
+	sc := NewShardedCache(0, 0, WithShards(8), opts)
+	defer sc.Close()
+	for i := 0; i < 1000; i++ {
+		sc.Set(fmt.Sprint("k", i), i, 0)
+	}
+	f := filepath.Join(t.TempDir(), "sharded.snap")
+	if err := sc.SaveToFile(f); err != nil {
+		t.Fatal(err)
+	}
+
+	other := NewShardedCache(0, 0, WithShards(32), opts)
+	defer other.Close()
+	if err := other.LoadFromFile(f); err != nil {
+		t.Fatal(err)
+	}
+	if n := other.Count(); n != 1000 {
+		t.Fatalf("Count() = %d, want 1000", n)
+	}
+	for i := 0; i < 1000; i++ {
+		if v, found := other.Get(fmt.Sprint("k", i)); !found || v != i {
+			t.Fatalf("Get(k%d) = %v, %v", i, v, found)
+		}
+	}
+
+	single := NewMiniCache(0, 0, WithSnapshotEncryption(key))
+	defer single.Close()
+	if err := single.LoadFromFile(f); err != nil || single.Count() != 1000 {
+		t.Fatalf("Minicache.LoadFromFile: err = %v, Count() = %d", err, single.Count())
+	}
+}
+
+func TestShardedIgnoresFileOptions(t *testing.T) {
+	dir := t.TempDir()
+	for name, opt := range map[string]Option{
+		"WithPersistence": WithPersistence(filepath.Join(dir, "p")),
+		"WithAutoSave":    WithAutoSave(filepath.Join(dir, "a"), time.Millisecond, nil),
+		"WithAOF":         WithAOF(filepath.Join(dir, "aof"), 0, nil),
+	} {
+		t.Run(name, func(t *testing.T) {
+			sc := NewShardedCache(0, 0, WithShards(2), WithShardOptions(opt))
+			sc.Set("k", 1, 0)
+			time.Sleep(5 * time.Millisecond)
+			if err := sc.Close(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("shards wrote %d files, want none", len(entries))
+	}
+}
+
+func TestShardedResize(t *testing.T) {
+	sc := NewShardedCache(0, 0, WithShards(4))
+	defer sc.Close()
+	for i := 0; i < 5000; i++ {
+		sc.Set(fmt.Sprint("k", i), i, 0)
+	}
+	for _, shards := range []int{16, 2, 64} {
+		done, err := sc.Resize(ResizeOptions{Shards: shards})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sc.Shards() != shards {
+			t.Fatalf("Shards() = %d, want %d", sc.Shards(), shards)
+		}
+		//迁移期间读写照常进行
+		for i := 0; i < 5000; i += 7 {
+			if v, found := sc.Get(fmt.Sprint("k", i)); !found || v != i {
+				t.Fatalf("Get(k%d) during resize = %v, %v", i, v, found)
+			}
+		}
+		<-done
+		if n := sc.Count(); n != 5000 {
+			t.Fatalf("Count() after resize to %d = %d, want 5000", shards, n)
+		}
+	}
+	for i := 0; i < 5000; i++ {
+		if v, found := sc.Get(fmt.Sprint("k", i)); !found || v != i {
+			t.Fatalf("Get(k%d) = %v, %v", i, v, found)
+		}
+	}
+}
+
+//迁移期间Range看到每个键恰好一次
+func TestShardedRangeDuringResize(t *testing.T) {
+	sc := NewShardedCache(0, 0, WithShards(8))
+	defer sc.Close()
+	for i := 0; i < 5000; i++ {
+		sc.Set(fmt.Sprint("k", i), i, 0)
+	}
+	for _, shards := range []int{32, 4} {
+		done, err := sc.Resize(ResizeOptions{Shards: shards})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for running := true; running; {
+			select {
+			case <-done:
+				running = false
+			default:
+			}
+			seen := map[string]int{}
+			sc.Range(func(k string, v interface{}) bool {
+				seen[k]++
+				return true
+			})
+			if len(seen) != 5000 {
+				t.Fatalf("Range during resize to %d saw %d keys, want 5000", shards, len(seen))
+			}
+			for k, n := range seen {
+				if n != 1 {
+					t.Fatalf("Range during resize to %d saw %s %d times", shards, k, n)
+				}
+			}
+		}
+	}
+}
+
+func TestShardedResizeConcurrent(t *testing.T) {
+	sc := NewShardedCache(0, 0, WithShards(2))
+	defer sc.Close()
+	const keys = 2000
+	for i := 0; i < keys; i++ {
+		sc.Set(fmt.Sprint("k", i), 0, 0)
+	}
+	//每个goroutine独占一部分键,递增计数,迁移不能丢失或回退任何一次写入
+	const workers = 4
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	counts := make([][]int, workers)
+	for w := 0; w < workers; w++ {
+		counts[w] = make([]int, keys)
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for {
+				for i := w; i < keys; i += workers {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					k := fmt.Sprint("k", i)
+					if v, found := sc.Get(k); !found || v != counts[w][i] {
+						t.Errorf("Get(%s) = %v, %v, want %d", k, v, found, counts[w][i])
+						return
+					}
+					counts[w][i]++
+					sc.Set(k, counts[w][i], 0)
+				}
+			}
+		}(w)
+	}
+	for _, shards := range []int{32, 4, 128, 1} {
+		done, err := sc.Resize(ResizeOptions{Shards: shards})
+		if err != nil {
+			t.Fatal(err)
+		}
+		<-done
+	}
+	close(stop)
+	wg.Wait()
+	if n := sc.Count(); n != keys {
+		t.Fatalf("Count() = %d, want %d", n, keys)
+	}
+}
+
+func TestShardedResizeCapacity(t *testing.T) {
+	sc := NewShardedCache(0, 0, WithShards(4), WithShardOptions(WithMaxEntries(100)))
+	defer sc.Close()
+	if st := sc.Stats(); st.MaxEntries != 400 {
+		t.Fatalf("MaxEntries = %d, want 400", st.MaxEntries)
+	}
+	for i := 0; i < 400; i++ {
+		sc.Set(fmt.Sprint("k", i), i, 0)
+	}
+	done, err := sc.Resize(ResizeOptions{MaxEntries: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	if st := sc.Stats(); st.MaxEntries != 100 || st.Entries > 100 {
+		t.Fatalf("Stats() = %+v after shrinking to 100 entries", st)
+	}
+	//改变分片数时总上限不变
+	if done, err = sc.Resize(ResizeOptions{Shards: 8}); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	if st := sc.Stats(); st.MaxEntries != 8*13 || st.Entries > 100 {
+		t.Fatalf("Stats() = %+v after resharding", st)
+	}
+	if _, err := sc.Resize(ResizeOptions{MaxEntries: -1}); err != nil {
+		t.Fatal(err)
+	}
+	if st := sc.Stats(); st.MaxEntries != 0 {
+		t.Fatalf("MaxEntries = %d, want unlimited", st.MaxEntries)
+	}
+}
+
+func TestShardedResizeBusy(t *testing.T) {
+	sc := NewShardedCache(0, 0, WithShards(2))
+	for i := 0; i < 20000; i++ {
+		sc.Set(fmt.Sprint("k", i), i, 0)
+	}
+	done, err := sc.Resize(ResizeOptions{Shards: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sc.Resize(ResizeOptions{Shards: 4}); err != ErrResizing && !isClosed(done) {
+		t.Fatalf("Resize during migration = %v, want ErrResizing", err)
+	}
+	//关闭时中止迁移
+	sc.Close()
+	<-done
+	if _, err := sc.Resize(ResizeOptions{Shards: 4}); err != ErrCacheClosed {
+		t.Fatalf("Resize after Close = %v, want ErrCacheClosed", err)
+	}
+}
+
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestShardedCacheAPI(t *testing.T) {
+	var c Cache = NewShardedCache(0, 0, WithShards(8), WithShardOptions(WithNamespaceMetrics("user:"), WithHotKeyTracking(0, 16)))
+	defer c.Close()
+	if err := c.SetWithPriority("user:1", 1, 0, 5); err != nil {
+		t.Fatal(err)
+	}
+	c.SetWithCost("user:2", 2, 10, 0)
+	if owner, ok := c.Claim("lock", "a", time.Minute); !ok || owner != "a" {
+		t.Fatalf(`Claim("lock", "a") = %q, %v`, owner, ok)
+	}
+	if owner, ok := c.Claim("lock", "b", time.Minute); ok || owner != "a" {
+		t.Fatalf(`Claim("lock", "b") = %q, %v, want a`, owner, ok)
+	}
+	c.SetIfGreater("max", 3, 0)
+	if c.SetIfGreater("max", 2, 0) || !c.SetIfGreater("max", 4, 0) {
+		t.Fatal("SetIfGreater did not keep the maximum")
+	}
+	got := c.GetMultiTouch([]string{"user:1", "user:2", "missing"}, time.Hour)
+	if len(got) != 2 || got["user:1"] != 1 || got["user:2"] != 2 {
+		t.Fatalf("GetMultiTouch() = %v", got)
+	}
+	if ttl, _ := c.TTL("user:1"); ttl <= 59*time.Minute {
+		t.Fatalf(`TTL("user:1") = %v after GetMultiTouch, want about 1h`, ttl)
+	}
+	if !c.ExpireAt("user:2", time.Now().Add(time.Minute)) {
+		t.Fatal("ExpireAt returned false")
+	}
+	if got := c.ExpiringWithin(2 * time.Minute); len(got) != 2 || got[0] != "lock" || got[1] != "user:2" {
+		t.Fatalf("ExpiringWithin() = %v, want [lock user:2]", got)
+	}
+	v, err := c.Memoize("memo", 0, func() (interface{}, error) { return "m", nil })
+	if err != nil || v != "m" {
+		t.Fatalf("Memoize() = %v, %v", v, err)
+	}
+	if meta, found := c.GetWithMeta("memo"); !found || meta.Source != SourceLoader {
+		t.Fatalf(`GetWithMeta("memo") = %+v, %v`, meta, found)
+	}
+	for i := 0; i < 3; i++ {
+		c.Get("user:1")
+	}
+	c.Get("user:2")
+	if top := c.TopKeys(1); len(top) != 1 || top[0].Key != "user:1" {
+		t.Fatalf("TopKeys(1) = %v, want user:1", top)
+	}
+	if ns := c.NamespaceStats()["user:"]; ns.Entries != 2 || ns.Hits < 4 {
+		t.Fatalf(`NamespaceStats()["user:"] = %+v`, ns)
+	}
+}
+
+func TestShardedSettingsSurviveResize(t *testing.T) {
+	sc := NewShardedCache(0, 0, WithShards(2))
+	defer sc.Close()
+	sc.RegisterLoader("db:", func(k string) (interface{}, time.Duration, error) {
+		return "loaded " + k, 0, nil
+	})
+	sc.SetPrefixExpiration("tmp:", time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sc.SetScoped(ctx, "scoped", 1)
+	sc.Pin("pinned")
+	done, err := sc.Resize(ResizeOptions{Shards: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	if v, err := sc.GetOrLoad("db:1"); err != nil || v != "loaded db:1" {
+		t.Fatalf(`GetOrLoad("db:1") = %v, %v`, v, err)
+	}
+	sc.Set("tmp:1", 1, DefaultExpiration)
+	if ttl, _ := sc.TTL("tmp:1"); ttl <= 0 || ttl > time.Minute {
+		t.Fatalf(`TTL("tmp:1") = %v, want the prefix expiration`, ttl)
+	}
+	s, n := sc.enter("pinned")
+	_, pinned := s.pins["pinned"]
+	exit(n)
+	if !pinned {
+		t.Fatal("pin was not moved to the new shard")
+	}
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	if _, found := sc.Get("scoped"); found {
+		t.Fatal("scoped key survived cancelling its context after resize")
+	}
+}
+
+func TestShardedEvents(t *testing.T) {
+	sc := NewShardedCache(0, 0, WithShards(8))
+	sub := sc.Subscribe(EventFilter{Ops: EventSet}, 100, DropNewest)
+	expired := sc.ExpiredChan(10)
+	for i := 0; i < 20; i++ {
+		sc.Set(fmt.Sprint("k", i), i, 0)
+	}
+	for i := 0; i < 20; i++ {
+		select {
+		case <-sub.Events():
+		case <-time.After(time.Second):
+			t.Fatalf("received %d of 20 set events", i)
+		}
+	}
+	sc.Set("short", 1, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	sc.DeleteExpired()
+	select {
+	case e := <-expired:
+		if e.Key != "short" {
+			t.Fatalf("ExpiredChan delivered %q, want short", e.Key)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no expired event")
+	}
+	closed := false
+	sc.OnClose(func() error {
+		closed = true
+		return nil
+	})
+	if err := sc.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !closed {
+		t.Fatal("OnClose function was not called")
+	}
+	select {
+	case _, ok := <-expired:
+		if ok {
+			t.Fatal("ExpiredChan delivered after Close")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ExpiredChan was not closed by Close")
+	}
+	if st := sc.GCStats(); st.Deleted != 1 {
+		t.Fatalf("GCStats().Deleted = %d, want 1", st.Deleted)
+	}
+}
+
+func TestShardedReplayAOF(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "cache.aof")
+	c := NewMiniCache(0, 0, WithAOF(f, 0, nil))
+	c.Set("a", 1, 0)
+	c.Flush()
+	for i := 0; i < 100; i++ {
+		c.Set(fmt.Sprint("k", i), i, 0)
+	}
+	c.Delete("k0")
+	c.Close()
+
+	sc := NewShardedCache(0, 0, WithShards(8))
+	defer sc.Close()
+	if err := sc.ReplayAOF(f); err != nil {
+		t.Fatal(err)
+	}
+	if n := sc.Count(); n != 99 {
+		t.Fatalf("Count() = %d, want 99", n)
+	}
+	if v, _ := sc.Get("k42"); v != 42 {
+		t.Fatalf(`Get("k42") = %v, want 42`, v)
+	}
+}
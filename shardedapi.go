@@ -0,0 +1,380 @@
+package minicache
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+//在所有分片上执行配置类的操作,并记录下来在Resize新建的分片上重放
+func (sc *ShardedCache) configure(fn func(s *Minicache)) {
+	sc.resizeMtx.Lock()
+	defer sc.resizeMtx.Unlock()
+	sc.settings = append(sc.settings, fn)
+	for _, s := range sc.tables.all() {
+		fn(s)
+	}
+}
+
+//第一个当前分片,用于读取所有分片相同的配置
+func (sc *ShardedCache) first() *Minicache {
+	return sc.tables.cur.Load().shards[0]
+}
+
+func (sc *ShardedCache) SetWithCost(k string, v interface{}, cost int64, d time.Duration) error {
+	s, n := sc.enter(k)
+	defer exit(n)
+	return s.SetWithCost(k, v, cost, d)
+}
+
+func (sc *ShardedCache) SetWithPriority(k string, v interface{}, d time.Duration, priority int) error {
+	s, n := sc.enter(k)
+	defer exit(n)
+	return s.SetWithPriority(k, v, d, priority)
+}
+
+//同Minicache.SetScoped,改变分片数时绑定随数据项迁移
+func (sc *ShardedCache) SetScoped(ctx context.Context, k string, v interface{}) error {
+	s, n := sc.enter(k)
+	defer exit(n)
+	return s.SetScoped(ctx, k, v)
+}
+
+func (sc *ShardedCache) Claim(k, ownerID string, d time.Duration) (currentOwner string, acquired bool) {
+	s, n := sc.enter(k)
+	defer exit(n)
+	return s.Claim(k, ownerID, d)
+}
+
+func (sc *ShardedCache) SetIfGreater(k string, v int64, d time.Duration) (updated bool) {
+	s, n := sc.enter(k)
+	defer exit(n)
+	return s.SetIfGreater(k, v, d)
+}
+
+func (sc *ShardedCache) SetIfLess(k string, v int64, d time.Duration) (updated bool) {
+	s, n := sc.enter(k)
+	defer exit(n)
+	return s.SetIfLess(k, v, d)
+}
+
+//等待所有分片缓冲区中的异步写入完成
+func (sc *ShardedCache) Wait() {
+	for _, s := range sc.tables.all() {
+		s.Wait()
+	}
+}
+
+//按分片分组,每个分片在一次加锁中读取并延长有效期
+//分组期间同时登记在多个分片上,迁移只等待登记结束,不会与这里的等待互相阻塞
+func (sc *ShardedCache) GetMultiTouch(keys []string, extend time.Duration) map[string]interface{} {
+	groups := map[*Minicache][]string{}
+	entered := make([]*int64, 0, len(keys))
+	defer func() {
+		for _, n := range entered {
+			exit(n)
+		}
+	}()
+	for _, k := range keys {
+		s, n := sc.enter(k)
+		entered = append(entered, n)
+		groups[s] = append(groups[s], k)
+	}
+	values := make(map[string]interface{}, len(keys))
+	for s, ks := range groups {
+		for k, v := range s.GetMultiTouch(ks, extend) {
+			values[k] = v
+		}
+	}
+	return values
+}
+
+func (sc *ShardedCache) GetWithMeta(k string) (ItemMeta, bool) {
+	s, n := sc.enter(k)
+	defer exit(n)
+	return s.GetWithMeta(k)
+}
+
+//同Minicache.GetOrLoad,加载期间键所在的分片不会开始迁移
+func (sc *ShardedCache) GetOrLoad(k string) (interface{}, error) {
+	s, n := sc.enter(k)
+	defer exit(n)
+	return s.GetOrLoad(k)
+}
+
+//同Minicache.Memoize,加载期间键所在的分片不会开始迁移
+func (sc *ShardedCache) Memoize(k string, d time.Duration, fn func() (interface{}, error)) (interface{}, error) {
+	s, n := sc.enter(k)
+	defer exit(n)
+	return s.Memoize(k, d, fn)
+}
+
+//为键前缀注册加载函数,所有分片共用
+func (sc *ShardedCache) RegisterLoader(prefix string, loader Loader) {
+	sc.configure(func(s *Minicache) {
+		s.RegisterLoader(prefix, loader)
+	})
+}
+
+//同Minicache.Warm,按键写入所在的分片
+func (sc *ShardedCache) Warm(ctx context.Context, keys []string, loader WarmLoader, concurrency int) error {
+	return warm(ctx, keys, loader, concurrency, func(k string, v interface{}, d time.Duration) error {
+		s, n := sc.enter(k)
+		defer exit(n)
+		return s.warmSet(k, v, d)
+	})
+}
+
+func (sc *ShardedCache) ExpireAt(k string, t time.Time) bool {
+	s, n := sc.enter(k)
+	defer exit(n)
+	return s.ExpireAt(k, t)
+}
+
+func (sc *ShardedCache) Revalidate(k string, d time.Duration) bool {
+	s, n := sc.enter(k)
+	defer exit(n)
+	return s.Revalidate(k, d)
+}
+
+//所有分片中未来d时长内将要过期的键,按过期时间从早到晚排序
+func (sc *ShardedCache) ExpiringWithin(d time.Duration) []string {
+	now := time.Now().UnixNano()
+	var list []expiringKey
+	for _, s := range sc.tables.all() {
+		list = s.expiring(list, now, now+int64(d))
+	}
+	keys := sortExpiring(list)
+	//迁移期间同一个数据项可能先在旧分片、后在新分片被看到,排序后相邻
+	out := keys[:0]
+	for i, k := range keys {
+		if i == 0 || k != keys[i-1] {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+func (sc *ShardedCache) SetDefaultExpiration(d time.Duration) {
+	sc.configure(func(s *Minicache) {
+		s.SetDefaultExpiration(d)
+	})
+}
+
+func (sc *ShardedCache) SetPrefixExpiration(prefix string, d time.Duration) {
+	sc.configure(func(s *Minicache) {
+		s.SetPrefixExpiration(prefix, d)
+	})
+}
+
+func (sc *ShardedCache) ClearPrefixExpiration(prefix string) {
+	sc.configure(func(s *Minicache) {
+		s.ClearPrefixExpiration(prefix)
+	})
+}
+
+func (sc *ShardedCache) OverrideTTL(keyOrPrefix string, d time.Duration, until time.Time) {
+	sc.configure(func(s *Minicache) {
+		s.OverrideTTL(keyOrPrefix, d, until)
+	})
+}
+
+func (sc *ShardedCache) ClearTTLOverride(keyOrPrefix string) {
+	sc.configure(func(s *Minicache) {
+		s.ClearTTLOverride(keyOrPrefix)
+	})
+}
+
+//同Minicache.Pin,改变分片数时固定随键迁移
+func (sc *ShardedCache) Pin(k string) {
+	s, n := sc.enter(k)
+	defer exit(n)
+	s.Pin(k)
+}
+
+func (sc *ShardedCache) Unpin(k string) {
+	s, n := sc.enter(k)
+	defer exit(n)
+	s.Unpin(k)
+}
+
+//注册清理统计回调,每轮清理完所有分片后以汇总的统计调用
+func (sc *ShardedCache) OnGC(fn func(GCStats)) {
+	sc.gcStats.onGC(fn)
+}
+
+//最近一轮清理所有分片的汇总统计
+func (sc *ShardedCache) GCStats() GCStats {
+	return sc.gcStats.get()
+}
+
+//注册关闭时执行的函数,Close关闭所有分片后按注册的相反顺序调用,此时缓存仍可读取
+func (sc *ShardedCache) OnClose(fn func() error) {
+	sc.closeHooks.add(fn)
+}
+
+//订阅所有分片的事件
+func (sc *ShardedCache) Subscribe(filter EventFilter, buffer int, policy BufferPolicy) *Subscription {
+	return sc.events.subscribe(filter, buffer, policy)
+}
+
+//所有分片的过期事件,Close后通道关闭
+func (sc *ShardedCache) ExpiredChan(buffer int) <-chan ExpiredEvent {
+	return sc.events.expiredChan(buffer, sc.done)
+}
+
+func (sc *ShardedCache) Trace(k string, w io.Writer, d time.Duration) {
+	sc.events.trace(k, w, d)
+}
+
+//汇总所有分片的延迟统计
+func (sc *ShardedCache) Latency(op LatencyOp) LatencySnapshot {
+	var total LatencySnapshot
+	for _, s := range sc.tables.all() {
+		total.add(s.Latency(op))
+	}
+	return total
+}
+
+//汇总所有分片的命名空间统计,未开启时返回nil
+func (sc *ShardedCache) NamespaceStats() map[string]NamespaceStats {
+	var total map[string]NamespaceStats
+	for _, s := range sc.tables.all() {
+		for ns, st := range s.NamespaceStats() {
+			if total == nil {
+				total = map[string]NamespaceStats{}
+			}
+			t := total[ns]
+			t.Hits += st.Hits
+			t.Misses += st.Misses
+			t.Entries += st.Entries
+			t.Bytes += st.Bytes
+			total[ns] = t
+		}
+	}
+	return total
+}
+
+//合并所有分片的sketch估计最近窗口内被访问过的不同键的数量,未开启时返回0
+func (sc *ShardedCache) KeyCardinality() uint64 {
+	if sc.first().cardinality == nil {
+		return 0
+	}
+	var merged hll
+	for _, s := range sc.tables.all() {
+		s.cardinality.mergeInto(&merged)
+	}
+	return merged.estimate()
+}
+
+//汇总所有分片中未读即过期或被淘汰的数据项数量,未开启时返回nil
+func (sc *ShardedCache) DeadEntries() map[string]uint64 {
+	if sc.first().deadEntries == nil {
+		return nil
+	}
+	total := map[string]uint64{}
+	for _, s := range sc.tables.all() {
+		for p, n := range s.DeadEntries() {
+			total[p] += n
+		}
+	}
+	return total
+}
+
+//所有分片中访问最多的n个键,每个键只在一个分片上计数
+func (sc *ShardedCache) TopKeys(n int) []HotKey {
+	if sc.first().hotKeys == nil {
+		return nil
+	}
+	counts := map[string]uint64{}
+	for _, s := range sc.tables.all() {
+		s.hotKeys.countInto(counts)
+	}
+	return topHotKeys(counts, n)
+}
+
+//汇总所有分片的模拟结果,MaxEntries为当前各分片模拟容量之和
+func (sc *ShardedCache) SimulationReport() []SimulationStats {
+	var report []SimulationStats
+	for _, s := range sc.tables.all() {
+		for i, st := range s.SimulationReport() {
+			if i == len(report) {
+				report = append(report, SimulationStats{Policy: st.Policy})
+			}
+			r := &report[i]
+			r.Hits += st.Hits
+			r.Misses += st.Misses
+			r.RealHits += st.RealHits
+			r.RealMisses += st.RealMisses
+		}
+	}
+	for _, s := range sc.tables.cur.Load().shards {
+		for i, st := range s.SimulationReport() {
+			report[i].MaxEntries += st.MaxEntries
+		}
+	}
+	return report
+}
+
+//按分片选项中的快照配置校验SaveToFile写入fileName所需的条件
+func (sc *ShardedCache) ValidatePersistence(fileName string) error {
+	sample, count := Item{Object: ""}, 0
+	for _, s := range sc.tables.all() {
+		item, n := s.sampleItem()
+		if count == 0 && n > 0 {
+			sample = item
+		}
+		count += n
+	}
+	return sc.first().validateSnapshot(fileName, sample, count)
+}
+
+//回放Minicache写入的追加日志,记录按键分配到各分片,清空记录清空所有分片
+func (sc *ShardedCache) ReplayAOF(fileName string) error {
+	first := sc.first()
+	if closed, err := first.closed(); closed {
+		return err
+	}
+	return replayAOF(fileName, max(first.loadBatchSize, 1), func(recs []aofRecord) {
+		for _, rec := range recs {
+			if rec.Op == aofFlush {
+				sc.Flush()
+				continue
+			}
+			s, n := sc.enter(rec.Key)
+			s.rwmtx.Lock()
+			s.applyAOF(rec)
+			s.unlock()
+			exit(n)
+		}
+	})
+}
+
+//分片不写追加日志,直接返回nil
+func (sc *ShardedCache) RewriteAOF() error {
+	return nil
+}
+
+func (sc *ShardedCache) Frozen() bool {
+	return sc.first().Frozen()
+}
+
+//优雅关闭:关闭所有分片并执行OnClose函数,等待进行中的gc和各分片的异步写入结束
+//ctx到期时返回ctx.Err(),剩余的关闭步骤在后台继续完成
+func (sc *ShardedCache) Shutdown(ctx context.Context) error {
+	result := make(chan error, 1)
+	go func() {
+		err := sc.Close()
+		sc.gcRunning.Wait()
+		for _, s := range sc.tables.all() {
+			s.background.Wait()
+		}
+		result <- err
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
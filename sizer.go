@@ -0,0 +1,118 @@
+package minicache
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+//估计值占用的内存字节数
+type Sizer interface {
+	Size(v interface{}) int64
+}
+
+//函数形式的Sizer
+type SizerFunc func(v interface{}) int64
+
+func (f SizerFunc) Size(v interface{}) int64 {
+	return f(v)
+}
+
+//每个数据项除键和值以外的固定开销:map槽位与Item结构,WithCoarseExpiration时为紧凑格式的结构
+const (
+	itemOverhead    = int64(unsafe.Sizeof(Item{})) + 16
+	compactOverhead = int64(unsafe.Sizeof(compactItem{})) + 16
+)
+
+//限制缓存的估计内存占用,超过bytes时按淘汰策略淘汰
+//每个数据项按键长度、固定开销和Sizer估计的值大小计入,默认使用基于反射的估计
+//与WithMaxCost共用同一个上限,后设置的生效;SetWithCost指定的开销不再估计
+func WithMaxMemory(bytes int64) Option {
+	return func(minic *Minicache) {
+		minic.maxCost = bytes
+		if minic.sizer == nil {
+			minic.sizer = reflectSizer{}
+		}
+	}
+}
+
+//替换WithMaxMemory使用的值大小估计方式
+func WithSizer(s Sizer) Option {
+	return func(minic *Minicache) {
+		minic.sizer = s
+	}
+}
+
+//基于反射递归估计值的大小,共享的指针只计一次
+//只是估计:不计map的桶开销和内存对齐,无法看到的unsafe.Pointer与chan缓冲区不计入
+type reflectSizer struct{}
+
+func (reflectSizer) Size(v interface{}) int64 {
+	if v == nil {
+		return 0
+	}
+	seen := map[uintptr]struct{}{}
+	rv := reflect.ValueOf(v)
+	return int64(rv.Type().Size()) + sizeOfRefs(rv, seen)
+}
+
+//v引用的、不在v自身内存中的数据大小
+func sizeOfRefs(v reflect.Value, seen map[uintptr]struct{}) int64 {
+	switch v.Kind() {
+	case reflect.String:
+		return int64(v.Len())
+	case reflect.Ptr:
+		if v.IsNil() || visited(v.Pointer(), seen) {
+			return 0
+		}
+		e := v.Elem()
+		return int64(e.Type().Size()) + sizeOfRefs(e, seen)
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		e := v.Elem()
+		return int64(e.Type().Size()) + sizeOfRefs(e, seen)
+	case reflect.Slice:
+		if v.IsNil() || visited(v.Pointer(), seen) {
+			return 0
+		}
+		n := int64(v.Cap()) * int64(v.Type().Elem().Size())
+		for i := 0; i < v.Len(); i++ {
+			n += sizeOfRefs(v.Index(i), seen)
+		}
+		return n
+	case reflect.Array:
+		var n int64
+		for i := 0; i < v.Len(); i++ {
+			n += sizeOfRefs(v.Index(i), seen)
+		}
+		return n
+	case reflect.Map:
+		if v.IsNil() || visited(v.Pointer(), seen) {
+			return 0
+		}
+		var n int64
+		iter := v.MapRange()
+		for iter.Next() {
+			k, e := iter.Key(), iter.Value()
+			n += int64(k.Type().Size()) + sizeOfRefs(k, seen)
+			n += int64(e.Type().Size()) + sizeOfRefs(e, seen)
+		}
+		return n
+	case reflect.Struct:
+		var n int64
+		for i := 0; i < v.NumField(); i++ {
+			n += sizeOfRefs(v.Field(i), seen)
+		}
+		return n
+	}
+	return 0
+}
+
+func visited(p uintptr, seen map[uintptr]struct{}) bool {
+	if _, ok := seen[p]; ok {
+		return true
+	}
+	seen[p] = struct{}{}
+	return false
+}
@@ -0,0 +1,38 @@
+package minicache
+
+import (
+	"testing"
+	"time"
+)
+
+//Sizer在写锁内panic后缓存仍然可用
+func TestSizerPanicReleasesLock(t *testing.T) {
+	c := NewMiniCache(0, 0, WithMaxMemory(1<<20), WithSizer(SizerFunc(func(v interface{}) int64 {
+		if v == "boom" {
+			panic("sizer failed")
+		}
+		return 1
+	})))
+	defer c.Close()
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("Set did not propagate the Sizer panic")
+			}
+		}()
+		c.Set("a", "boom", 0)
+	}()
+	done := make(chan struct{})
+	go func() {
+		c.Set("b", "ok", 0)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Set blocked after a Sizer panic")
+	}
+	if v, found := c.Get("b"); !found || v != "ok" {
+		t.Fatalf("Get(b) = %v, %v", v, found)
+	}
+}
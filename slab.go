@@ -0,0 +1,223 @@
+package minicache
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+)
+
+//字节段存储引擎,值序列化后追加写入固定大小的[]byte段,索引只包含整数,GC不需要扫描数据项
+//段按环形顺序复用,写满后覆盖最旧的段,其中的数据项随之淘汰;删除和覆盖只移除索引,空间在段复用时回收
+//键的64位哈希相同时后写入的键覆盖先写入的键
+//SlabCache是独立的类型,不作为Minicache的存储后端,也没有对应的选项:值只能是[]byte,
+//不支持淘汰策略、回调、事件和持久化等Minicache的功能,需要这些功能时使用Minicache
+type SlabCache struct {
+	mtx               sync.RWMutex
+	segments          [][]byte
+	head              int               //当前写入的段
+	index             map[uint64]uint64 //键的哈希到位置(段号<<32|段内偏移)
+	defaultExpiration time.Duration
+	mapped            [][]byte //堆外模式下映射的内存,Close时释放
+	closed            bool
+}
+
+//数据项头部:过期时间、键的哈希、键长度、值长度
+const slabHeaderSize = 8 + 8 + 4 + 4
+
+//创建字节段缓存,总容量为segments*segmentSize字节,单个数据项(包括头部和键)不能超过segmentSize
+func NewSlabCache(defaultExpiration time.Duration, segments, segmentSize int) *SlabCache {
+	if segments < 2 {
+		segments = 2
+	}
+	sc := &SlabCache{
+		segments:          make([][]byte, segments),
+		index:             map[uint64]uint64{},
+		defaultExpiration: defaultExpiration,
+	}
+	for i := range sc.segments {
+		sc.segments[i] = make([]byte, 0, segmentSize)
+	}
+	return sc
+}
+
+//创建使用堆外内存的字节段缓存(实验性),段通过匿名mmap分配,不计入Go堆,也不影响GC标记时间
+//删除、覆盖和过期的数据项只移除索引,映射的内存不会因此归还:Linux上Flush将物理页归还给系统,
+//其它平台只有Close释放内存。不再使用时必须调用Close;Close之后写入返回ErrCacheClosed,读取返回不存在
+func NewOffHeapSlabCache(defaultExpiration time.Duration, segments, segmentSize int) (*SlabCache, error) {
+	if segments < 2 {
+		segments = 2
+	}
+	sc := &SlabCache{
+		segments:          make([][]byte, segments),
+		index:             map[uint64]uint64{},
+		defaultExpiration: defaultExpiration,
+	}
+	for i := range sc.segments {
+		b, err := mapOffHeap(segmentSize)
+		if err != nil {
+			sc.Close()
+			return nil, err
+		}
+		sc.mapped = append(sc.mapped, b)
+		sc.segments[i] = b[:0]
+	}
+	return sc, nil
+}
+
+//释放堆外内存并关闭缓存,可重复调用
+func (sc *SlabCache) Close() error {
+	sc.mtx.Lock()
+	defer sc.mtx.Unlock()
+	if sc.closed {
+		return nil
+	}
+	sc.closed = true
+	var err error
+	for _, b := range sc.mapped {
+		if e := unmapOffHeap(b); e != nil && err == nil {
+			err = e
+		}
+	}
+	sc.mapped = nil
+	sc.segments = nil
+	sc.index = map[uint64]uint64{}
+	return err
+}
+
+func (sc *SlabCache) expiration(d time.Duration) int64 {
+	switch d {
+	case NoExpiration:
+		return 0
+	case DefaultExpiration:
+		d = sc.defaultExpiration
+	}
+	if d > 0 {
+		return time.Now().Add(d).UnixNano()
+	}
+	return 0
+}
+
+//写入值,值被复制到段中
+func (sc *SlabCache) Set(k string, v []byte, d time.Duration) error {
+	h := fnv64a(k)
+	e := sc.expiration(d)
+	sc.mtx.Lock()
+	defer sc.mtx.Unlock()
+	if sc.closed {
+		return ErrCacheClosed
+	}
+	size := slabHeaderSize + len(k) + len(v)
+	if size > cap(sc.segments[0]) {
+		return fmt.Errorf("%w: %s", ErrValueTooLarge, k)
+	}
+	seg := sc.segments[sc.head]
+	if len(seg)+size > cap(seg) {
+		sc.head = (sc.head + 1) % len(sc.segments)
+		sc.recycle(sc.head)
+		seg = sc.segments[sc.head]
+	}
+	off := len(seg)
+	var hdr [slabHeaderSize]byte
+	binary.LittleEndian.PutUint64(hdr[0:], uint64(e))
+	binary.LittleEndian.PutUint64(hdr[8:], h)
+	binary.LittleEndian.PutUint32(hdr[16:], uint32(len(k)))
+	binary.LittleEndian.PutUint32(hdr[20:], uint32(len(v)))
+	seg = append(seg, hdr[:]...)
+	seg = append(seg, k...)
+	seg = append(seg, v...)
+	sc.segments[sc.head] = seg
+	sc.index[h] = slabPos(sc.head, off)
+	return nil
+}
+
+func slabPos(seg, off int) uint64 {
+	return uint64(seg)<<32 | uint64(off)
+}
+
+//清空段i,删除仍指向其中数据项的索引,调用方持有写锁
+func (sc *SlabCache) recycle(i int) {
+	seg := sc.segments[i]
+	for off := 0; off < len(seg); {
+		h := binary.LittleEndian.Uint64(seg[off+8:])
+		kl := int(binary.LittleEndian.Uint32(seg[off+16:]))
+		vl := int(binary.LittleEndian.Uint32(seg[off+20:]))
+		if pos, ok := sc.index[h]; ok && pos == slabPos(i, off) {
+			delete(sc.index, h)
+		}
+		off += slabHeaderSize + kl + vl
+	}
+	sc.segments[i] = seg[:0]
+}
+
+//读取数据项,k对应位置上的键和k不同(哈希冲突)或已过期时返回false,调用方持有读锁
+func (sc *SlabCache) entry(k string) (val []byte, ok bool) {
+	pos, found := sc.index[fnv64a(k)]
+	if !found {
+		return nil, false
+	}
+	seg := sc.segments[pos>>32]
+	off := int(uint32(pos))
+	e := int64(binary.LittleEndian.Uint64(seg[off:]))
+	kl := int(binary.LittleEndian.Uint32(seg[off+16:]))
+	vl := int(binary.LittleEndian.Uint32(seg[off+20:]))
+	start := off + slabHeaderSize
+	if string(seg[start:start+kl]) != k {
+		return nil, false
+	}
+	if e > 0 && time.Now().UnixNano() > e {
+		return nil, false
+	}
+	return seg[start+kl : start+kl+vl], true
+}
+
+//读取值的副本,返回的切片不引用段内存,覆盖、Flush和Close之后仍然有效
+func (sc *SlabCache) Get(k string) ([]byte, bool) {
+	sc.mtx.RLock()
+	defer sc.mtx.RUnlock()
+	val, ok := sc.entry(k)
+	if !ok {
+		return nil, false
+	}
+	return append([]byte(nil), val...), true
+}
+
+//将值复制追加到dst后返回,dst容量足够时不分配内存,返回的切片同样不引用段内存
+func (sc *SlabCache) GetTo(k string, dst []byte) ([]byte, bool) {
+	sc.mtx.RLock()
+	defer sc.mtx.RUnlock()
+	val, ok := sc.entry(k)
+	if !ok {
+		return dst, false
+	}
+	return append(dst, val...), true
+}
+
+func (sc *SlabCache) Delete(k string) {
+	sc.mtx.Lock()
+	defer sc.mtx.Unlock()
+	if _, ok := sc.entry(k); ok {
+		delete(sc.index, fnv64a(k))
+	}
+}
+
+//索引中的数据项数量,包括已过期但所在段尚未复用的数据项
+func (sc *SlabCache) Count() int {
+	sc.mtx.RLock()
+	defer sc.mtx.RUnlock()
+	return len(sc.index)
+}
+
+//清空所有段,已分配的段保留复用,Linux上堆外模式的物理内存归还给系统
+func (sc *SlabCache) Flush() {
+	sc.mtx.Lock()
+	defer sc.mtx.Unlock()
+	for i := range sc.segments {
+		sc.segments[i] = sc.segments[i][:0]
+	}
+	for _, b := range sc.mapped {
+		releaseOffHeap(b)
+	}
+	sc.head = 0
+	sc.index = map[uint64]uint64{}
+}
@@ -0,0 +1,110 @@
+package minicache
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSlabRoundTrip(t *testing.T) {
+	sc := NewSlabCache(NoExpiration, 4, 1024)
+	if err := sc.Set("a", []byte("1"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := sc.Set("b", []byte("22"), 0); err != nil {
+		t.Fatal(err)
+	}
+	v, ok := sc.Get("a")
+	if !ok || string(v) != "1" {
+		t.Fatalf("Get(a) = %q, %v", v, ok)
+	}
+	v[0] = 'x'
+	if v, _ := sc.Get("a"); string(v) != "1" {
+		t.Fatalf("Get returned segment memory, got %q after modifying the result", v)
+	}
+	dst := make([]byte, 0, 16)
+	if v, ok := sc.GetTo("b", dst); !ok || string(v) != "22" {
+		t.Fatalf("GetTo(b) = %q, %v", v, ok)
+	}
+	if err := sc.Set("a", []byte("333"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := sc.Get("a"); string(v) != "333" {
+		t.Fatalf("Get(a) after overwrite = %q", v)
+	}
+	sc.Delete("a")
+	if _, ok := sc.Get("a"); ok || sc.Count() != 1 {
+		t.Fatalf("Get(a) after Delete found = %v, Count() = %d", ok, sc.Count())
+	}
+	if err := sc.Set("big", make([]byte, 1024), 0); !errors.Is(err, ErrValueTooLarge) {
+		t.Fatalf("Set(big) = %v, want ErrValueTooLarge", err)
+	}
+	sc.Flush()
+	if _, ok := sc.Get("b"); ok || sc.Count() != 0 {
+		t.Fatal("Flush left items behind")
+	}
+}
+
+func TestSlabRecycle(t *testing.T) {
+	//每个段正好容纳两个数据项
+	item := slabHeaderSize + len("k0") + 8
+	sc := NewSlabCache(NoExpiration, 3, 2*item)
+	for i := 0; i < 6; i++ {
+		sc.Set(fmt.Sprintf("k%d", i), make([]byte, 8), 0)
+	}
+	if sc.Count() != 6 {
+		t.Fatalf("Count() = %d, want 6", sc.Count())
+	}
+	//写入第7个数据项时回绕到第0段,k0和k1随之淘汰
+	sc.Set("k6", make([]byte, 8), 0)
+	for i := 0; i < 7; i++ {
+		_, ok := sc.Get(fmt.Sprintf("k%d", i))
+		if want := i >= 2; ok != want {
+			t.Fatalf("Get(k%d) found = %v, want %v", i, ok, want)
+		}
+	}
+	if sc.Count() != 5 {
+		t.Fatalf("Count() = %d, want 5", sc.Count())
+	}
+	//k2被覆盖后新位置在第0段,复用第1段时不能删除新位置的索引
+	sc.Set("k2", []byte("newvalue"), 0)
+	sc.Set("k7", make([]byte, 8), 0)
+	if v, ok := sc.Get("k2"); !ok || string(v) != "newvalue" {
+		t.Fatalf("Get(k2) = %q, %v", v, ok)
+	}
+	if _, ok := sc.Get("k3"); ok {
+		t.Fatal("k3 survived the reuse of its segment")
+	}
+}
+
+func TestSlabHashCollision(t *testing.T) {
+	sc := NewSlabCache(NoExpiration, 2, 1024)
+	sc.Set("a", []byte("1"), 0)
+	//模拟b和a的哈希相同:b的索引指向a的数据项
+	sc.index[fnv64a("b")] = sc.index[fnv64a("a")]
+	if _, ok := sc.Get("b"); ok {
+		t.Fatal("Get(b) returned the value of a colliding key")
+	}
+	sc.Delete("b")
+	if v, ok := sc.Get("a"); !ok || string(v) != "1" {
+		t.Fatalf("Delete(b) removed a colliding key, Get(a) = %q, %v", v, ok)
+	}
+}
+
+func TestSlabExpiration(t *testing.T) {
+	sc := NewSlabCache(20*time.Millisecond, 2, 1024)
+	sc.Set("default", []byte("1"), DefaultExpiration)
+	sc.Set("short", []byte("1"), 10*time.Millisecond)
+	sc.Set("forever", []byte("1"), NoExpiration)
+	time.Sleep(30 * time.Millisecond)
+	for k, want := range map[string]bool{"default": false, "short": false, "forever": true} {
+		if _, ok := sc.Get(k); ok != want {
+			t.Fatalf("Get(%s) found = %v, want %v", k, ok, want)
+		}
+	}
+	sc.Close()
+	if err := sc.Set("a", []byte("1"), 0); !errors.Is(err, ErrCacheClosed) {
+		t.Fatalf("Set after Close = %v, want ErrCacheClosed", err)
+	}
+}
@@ -0,0 +1,89 @@
+package minicache
+
+import "container/list"
+
+//受保护段占容量的比例
+const slruProtectedRatio = 0.8
+
+type slruEntry struct {
+	protected bool
+	elem      *list.Element
+}
+
+//分段LRU:新键进入试用段,再次命中后晋升到受保护段,淘汰优先从试用段进行
+//一次性扫描的键只停留在试用段,不会冲掉受保护段中的热点数据
+type slru struct {
+	probation    *list.List //表头为最近访问
+	protected    *list.List
+	protectedCap int
+	entries      map[string]*slruEntry
+}
+
+func newSLRU(capacity int) *slru {
+	return &slru{
+		probation:    list.New(),
+		protected:    list.New(),
+		protectedCap: max(int(float64(capacity)*slruProtectedRatio), 1),
+		entries:      map[string]*slruEntry{},
+	}
+}
+
+func (p *slru) add(k string) {
+	if _, ok := p.entries[k]; ok {
+		p.access(k)
+		return
+	}
+	p.entries[k] = &slruEntry{elem: p.probation.PushFront(k)}
+}
+
+func (p *slru) access(k string) {
+	e, ok := p.entries[k]
+	if !ok {
+		return
+	}
+	if e.protected {
+		p.protected.MoveToFront(e.elem)
+		return
+	}
+	p.probation.Remove(e.elem)
+	e.protected = true
+	e.elem = p.protected.PushFront(k)
+	//受保护段超出容量时,最久未访问的键降回试用段
+	if p.protected.Len() > p.protectedCap {
+		back := p.protected.Back()
+		bk := back.Value.(string)
+		p.protected.Remove(back)
+		be := p.entries[bk]
+		be.protected = false
+		be.elem = p.probation.PushFront(bk)
+	}
+}
+
+func (p *slru) remove(k string) {
+	e, ok := p.entries[k]
+	if !ok {
+		return
+	}
+	if e.protected {
+		p.protected.Remove(e.elem)
+	} else {
+		p.probation.Remove(e.elem)
+	}
+	delete(p.entries, k)
+}
+
+func (p *slru) victim() (string, bool) {
+	if e := p.probation.Back(); e != nil {
+		return e.Value.(string), true
+	}
+	if e := p.protected.Back(); e != nil {
+		return e.Value.(string), true
+	}
+	return "", false
+}
+
+func (p *slru) reset() {
+	p.probation.Init()
+	p.protected.Init()
+	p.entries = map[string]*slruEntry{}
+}
@@ -0,0 +1,71 @@
+package minicache
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+)
+
+//快照压缩算法,记录在文件头标志的低4位
+type SnapshotCompression uint16
+
+const (
+	CompressionNone SnapshotCompression = iota //不压缩
+	CompressionGzip                            //gzip,标准库实现
+	CompressionZstd                            //zstd,标准库没有实现,需要先通过RegisterSnapshotCompression注册
+)
+
+const compressionMask = 0x000f
+
+//压缩算法的实现,level为0时使用算法的默认级别
+type snapshotCodec struct {
+	newWriter func(w io.Writer, level int) (io.WriteCloser, error)
+	newReader func(r io.Reader) (io.ReadCloser, error)
+}
+
+var (
+	codecsMtx sync.RWMutex
+	codecs    = map[SnapshotCompression]snapshotCodec{
+		CompressionGzip: {
+			newWriter: func(w io.Writer, level int) (io.WriteCloser, error) {
+				if level == 0 {
+					level = gzip.DefaultCompression
+				}
+				return gzip.NewWriterLevel(w, level)
+			},
+			newReader: func(r io.Reader) (io.ReadCloser, error) {
+				return gzip.NewReader(r)
+			},
+		},
+	}
+)
+
+//Save写入的快照使用的压缩算法和级别,Load按文件头自动识别,不受此选项影响
+//level为0时使用算法的默认级别
+func WithSnapshotCompression(c SnapshotCompression, level int) Option {
+	return func(minic *Minicache) {
+		minic.snapCompression = c
+		minic.snapLevel = level
+	}
+}
+
+//注册或替换压缩算法的实现,例如接入第三方zstd库
+func RegisterSnapshotCompression(c SnapshotCompression, newWriter func(w io.Writer, level int) (io.WriteCloser, error), newReader func(r io.Reader) (io.ReadCloser, error)) {
+	if c == CompressionNone || c&^compressionMask != 0 {
+		panic(fmt.Sprintf("minicache: invalid snapshot compression %d", c))
+	}
+	codecsMtx.Lock()
+	defer codecsMtx.Unlock()
+	codecs[c] = snapshotCodec{newWriter: newWriter, newReader: newReader}
+}
+
+func codecOf(c SnapshotCompression) (snapshotCodec, error) {
+	codecsMtx.RLock()
+	defer codecsMtx.RUnlock()
+	codec, ok := codecs[c]
+	if !ok {
+		return snapshotCodec{}, fmt.Errorf("snapshot compression %d is not registered", c)
+	}
+	return codec, nil
+}
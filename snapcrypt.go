@@ -0,0 +1,104 @@
+package minicache
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+//文件头标志中表示快照已加密的位
+const encryptedFlag = 0x0010
+
+//快照加密密钥,密钥长度为16、24或32字节,分别对应AES-128、AES-192、AES-256
+//轮换密钥时CurrentKey返回新密钥,Key仍能按编号返回旧密钥以读取轮换前写入的快照
+type SnapshotKeyProvider interface {
+	CurrentKey() (id uint32, key []byte, err error) //Save使用的密钥及其编号,编号写入文件
+	Key(id uint32) ([]byte, error)                  //Load按文件中的编号查找密钥
+}
+
+//固定密钥,编号为0
+type staticSnapshotKey []byte
+
+func (k staticSnapshotKey) CurrentKey() (uint32, []byte, error) {
+	return 0, k, nil
+}
+
+func (k staticSnapshotKey) Key(id uint32) ([]byte, error) {
+	if id != 0 {
+		return nil, fmt.Errorf("unknown snapshot key %d", id)
+	}
+	return k, nil
+}
+
+//Save使用AES-GCM加密快照,Load解密并校验文件头和数据未被篡改
+func WithSnapshotEncryption(key []byte) Option {
+	return WithSnapshotKeyProvider(staticSnapshotKey(append([]byte(nil), key...)))
+}
+
+//同WithSnapshotEncryption,密钥由p提供,用于密钥轮换
+func WithSnapshotKeyProvider(p SnapshotKeyProvider) Option {
+	return func(minic *Minicache) {
+		minic.snapKeys = p
+	}
+}
+
+func newSnapshotAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+//写入密钥编号、随机nonce和密文,header作为附加数据参与认证
+func sealSnapshot(w io.Writer, header []byte, keys SnapshotKeyProvider, plaintext []byte) error {
+	id, key, err := keys.CurrentKey()
+	if err != nil {
+		return err
+	}
+	aead, err := newSnapshotAEAD(key)
+	if err != nil {
+		return err
+	}
+	prefix := make([]byte, 4+aead.NonceSize())
+	binary.LittleEndian.PutUint32(prefix, id)
+	if _, err := io.ReadFull(rand.Reader, prefix[4:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(prefix); err != nil {
+		return err
+	}
+	_, err = w.Write(aead.Seal(nil, prefix[4:], plaintext, append(header[:len(header):len(header)], prefix[:4]...)))
+	return err
+}
+
+//解密sealSnapshot写入的数据,密钥错误或数据被篡改时返回ErrDecryptSnapshot
+func openSnapshot(data, header []byte, keys SnapshotKeyProvider) ([]byte, error) {
+	if keys == nil {
+		return nil, fmt.Errorf("%w: no key configured", ErrDecryptSnapshot)
+	}
+	if len(data) < 4 {
+		return nil, fmt.Errorf("%w: truncated", ErrCorruptSnapshot)
+	}
+	id := binary.LittleEndian.Uint32(data)
+	key, err := keys.Key(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecryptSnapshot, err)
+	}
+	aead, err := newSnapshotAEAD(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecryptSnapshot, err)
+	}
+	if len(data) < 4+aead.NonceSize() {
+		return nil, fmt.Errorf("%w: truncated", ErrCorruptSnapshot)
+	}
+	nonce, ciphertext := data[4:4+aead.NonceSize()], data[4+aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, append(header[:len(header):len(header)], data[:4]...))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecryptSnapshot, err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,176 @@
+package minicache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"time"
+)
+
+//快照文件头:魔数、格式版本、标志(低4位为压缩算法,第5位表示加密)、写入时间和数据项数量,小端序
+const (
+	snapshotMagic      = "MNCS"
+	snapshotVersion    = 2 //1:无校验和 2:末尾附加CRC32
+	snapshotHeaderSize = 24
+	maxSnapshotPresize = 1 << 20 //按文件头预分配的上限,避免损坏的文件头导致过量分配
+)
+
+var snapshotTable = crc32.MakeTable(crc32.Castagnoli)
+
+//快照文件头信息
+type SnapshotHeader struct {
+	Version     int
+	Compression SnapshotCompression
+	Encrypted   bool
+	Created     time.Time //Save开始编码的时间
+	Items       int       //写入时的数据项数量
+}
+
+func snapshotHeaderBytes(items int, flags uint16) []byte {
+	buf := make([]byte, snapshotHeaderSize)
+	copy(buf[:4], snapshotMagic)
+	binary.LittleEndian.PutUint16(buf[4:6], snapshotVersion)
+	binary.LittleEndian.PutUint16(buf[6:8], flags)
+	binary.LittleEndian.PutUint64(buf[8:16], uint64(time.Now().UnixNano()))
+	binary.LittleEndian.PutUint64(buf[16:24], uint64(items))
+	return buf
+}
+
+//依次写入文件头、gob编码的数据项和覆盖前两者的CRC32校验和,数据项按c压缩,文件头不压缩
+//keys不为nil时数据项压缩后整体加密,需要在内存中保留完整的明文
+func encodeSnapshot(w io.Writer, items map[string]Item, c SnapshotCompression, level int, keys SnapshotKeyProvider) error {
+	h := crc32.New(snapshotTable)
+	mw := io.MultiWriter(w, h)
+	flags := uint16(c)
+	var body io.Writer = mw
+	var plaintext *bytes.Buffer
+	if keys != nil {
+		flags |= encryptedFlag
+		plaintext = &bytes.Buffer{}
+		body = plaintext
+	}
+	payload := body
+	var zw io.WriteCloser
+	if c != CompressionNone {
+		codec, err := codecOf(c)
+		if err != nil {
+			return err
+		}
+		if zw, err = codec.newWriter(body, level); err != nil {
+			return err
+		}
+		payload = zw
+	}
+	header := snapshotHeaderBytes(len(items), flags)
+	if _, err := mw.Write(header); err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(payload).Encode(&items); err != nil {
+		return err
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return err
+		}
+	}
+	if plaintext != nil {
+		if err := sealSnapshot(mw, header, keys, plaintext.Bytes()); err != nil {
+			return err
+		}
+	}
+	var sum [crc32.Size]byte
+	binary.LittleEndian.PutUint32(sum[:], h.Sum32())
+	_, err := w.Write(sum[:])
+	return err
+}
+
+//读取并校验文件头,魔数不符返回ErrNotASnapshot,版本或标志无法识别返回ErrUnsupportedVersion
+func ReadSnapshotHeader(r io.Reader) (SnapshotHeader, error) {
+	_, hdr, err := readSnapshotHeader(r)
+	return hdr, err
+}
+
+//返回文件头原始字节,用于计算校验和
+func readSnapshotHeader(r io.Reader) ([]byte, SnapshotHeader, error) {
+	buf := make([]byte, snapshotHeaderSize)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, SnapshotHeader{}, ErrNotASnapshot
+		}
+		return nil, SnapshotHeader{}, err
+	}
+	if string(buf[:4]) != snapshotMagic {
+		return nil, SnapshotHeader{}, ErrNotASnapshot
+	}
+	version := binary.LittleEndian.Uint16(buf[4:6])
+	flags := binary.LittleEndian.Uint16(buf[6:8])
+	if version < 1 || version > snapshotVersion || flags&^(compressionMask|encryptedFlag) != 0 || (version < 2 && flags != 0) {
+		return nil, SnapshotHeader{}, fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
+	}
+	return buf, SnapshotHeader{
+		Version:     int(version),
+		Compression: SnapshotCompression(flags & compressionMask),
+		Encrypted:   flags&encryptedFlag != 0,
+		Created:     time.Unix(0, int64(binary.LittleEndian.Uint64(buf[8:16]))),
+		Items:       int(binary.LittleEndian.Uint64(buf[16:24])),
+	}, nil
+}
+
+//解码快照,没有文件头的旧格式按gob直接解码,解码失败时返回ErrNotASnapshot
+//带校验和的格式先读入全部数据并校验,不一致时返回ErrCorruptSnapshot,不会解码出部分数据
+//加密的快照用keys解密,认证失败时返回ErrDecryptSnapshot
+func decodeSnapshot(r io.Reader, keys SnapshotKeyProvider) (map[string]Item, error) {
+	br := bufio.NewReader(r)
+	items := make(map[string]Item, 0)
+	if magic, err := br.Peek(len(snapshotMagic)); err != nil || !bytes.Equal(magic, []byte(snapshotMagic)) {
+		if err := gob.NewDecoder(br).Decode(&items); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrNotASnapshot, err)
+		}
+		return items, nil
+	}
+	raw, hdr, err := readSnapshotHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	var payload io.Reader = br
+	if hdr.Version >= 2 {
+		data, err := io.ReadAll(br)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) < crc32.Size {
+			return nil, fmt.Errorf("%w: truncated", ErrCorruptSnapshot)
+		}
+		data, sum := data[:len(data)-crc32.Size], binary.LittleEndian.Uint32(data[len(data)-crc32.Size:])
+		if crc32.Update(crc32.Checksum(raw, snapshotTable), snapshotTable, data) != sum {
+			return nil, fmt.Errorf("%w: checksum mismatch", ErrCorruptSnapshot)
+		}
+		if hdr.Encrypted {
+			if data, err = openSnapshot(data, raw, keys); err != nil {
+				return nil, err
+			}
+		}
+		payload = bytes.NewReader(data)
+	}
+	if hdr.Compression != CompressionNone {
+		codec, err := codecOf(hdr.Compression)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnsupportedVersion, err)
+		}
+		zr, err := codec.newReader(payload)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		payload = zr
+	}
+	items = make(map[string]Item, max(min(hdr.Items, maxSnapshotPresize), 0))
+	if err := gob.NewDecoder(payload).Decode(&items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,175 @@
+package minicache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+//按编号保存的密钥,current为Save使用的编号
+type rotatingKeys struct {
+	current uint32
+	keys    map[uint32][]byte
+}
+
+func (p *rotatingKeys) CurrentKey() (uint32, []byte, error) {
+	return p.current, p.keys[p.current], nil
+}
+
+func (p *rotatingKeys) Key(id uint32) ([]byte, error) {
+	if k, ok := p.keys[id]; ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown key %d", id)
+}
+
+func saveSnapshot(t *testing.T, opts ...Option) []byte {
+	t.Helper()
+	c := NewMiniCache(0, 0, opts...)
+	defer c.Close()
+	c.Set("a", 1, 0)
+	c.Set("b", "two", time.Hour)
+	var buf bytes.Buffer
+	if err := c.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func loadSnapshot(data []byte, opts ...Option) (*Minicache, error) {
+	c := NewMiniCache(0, 0, opts...)
+	return c, c.Load(bytes.NewReader(data))
+}
+
+func TestSnapshotHeader(t *testing.T) {
+	start := time.Now().Add(-time.Second)
+	data := saveSnapshot(t, WithSnapshotCompression(CompressionGzip, 0), WithSnapshotEncryption(make([]byte, 16)))
+	hdr, err := ReadSnapshotHeader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Version != snapshotVersion || hdr.Compression != CompressionGzip || !hdr.Encrypted || hdr.Items != 2 {
+		t.Fatalf("ReadSnapshotHeader() = %+v", hdr)
+	}
+	if hdr.Created.Before(start) || hdr.Created.After(time.Now()) {
+		t.Fatalf("Created = %v, want about now", hdr.Created)
+	}
+	if _, err := ReadSnapshotHeader(bytes.NewReader([]byte("not a snapshot at all"))); !errors.Is(err, ErrNotASnapshot) {
+		t.Fatalf("ReadSnapshotHeader(garbage) = %v, want ErrNotASnapshot", err)
+	}
+	bad := append([]byte(nil), data...)
+	bad[4] = 99
+	if _, err := ReadSnapshotHeader(bytes.NewReader(bad)); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("ReadSnapshotHeader(version 99) = %v, want ErrUnsupportedVersion", err)
+	}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	key := make([]byte, 32)
+	for name, opts := range map[string][]Option{
+		"Plain":     nil,
+		"Gzip":      {WithSnapshotCompression(CompressionGzip, 9)},
+		"Encrypted": {WithSnapshotEncryption(key)},
+		"Both":      {WithSnapshotCompression(CompressionGzip, 0), WithSnapshotEncryption(key)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c, err := loadSnapshot(saveSnapshot(t, opts...), opts...)
+			defer c.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v, _ := c.Get("a"); v != 1 {
+				t.Fatalf(`Get("a") = %v, want 1`, v)
+			}
+			if ttl, found := c.TTL("b"); !found || ttl <= 59*time.Minute {
+				t.Fatalf(`TTL("b") = %v, %v, want about 1h`, ttl, found)
+			}
+		})
+	}
+}
+
+func TestSnapshotCorrupt(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"Plain":     nil,
+		"Encrypted": {WithSnapshotEncryption(make([]byte, 16))},
+	} {
+		t.Run(name, func(t *testing.T) {
+			data := saveSnapshot(t, opts...)
+			data[len(data)/2] ^= 0xff
+			c, err := loadSnapshot(data, opts...)
+			defer c.Close()
+			if !errors.Is(err, ErrCorruptSnapshot) {
+				t.Fatalf("Load() = %v, want ErrCorruptSnapshot", err)
+			}
+			if n := c.Count(); n != 0 {
+				t.Fatalf("Count() = %d after failed Load, want 0", n)
+			}
+		})
+	}
+	c, err := loadSnapshot(saveSnapshot(t)[:snapshotHeaderSize+2])
+	defer c.Close()
+	if !errors.Is(err, ErrCorruptSnapshot) {
+		t.Fatalf("Load(truncated) = %v, want ErrCorruptSnapshot", err)
+	}
+}
+
+func TestSnapshotDecrypt(t *testing.T) {
+	data := saveSnapshot(t, WithSnapshotEncryption(bytes.Repeat([]byte{1}, 32)))
+	for name, opts := range map[string][]Option{
+		"WrongKey": {WithSnapshotEncryption(bytes.Repeat([]byte{2}, 32))},
+		"NoKey":    nil,
+	} {
+		t.Run(name, func(t *testing.T) {
+			c, err := loadSnapshot(data, opts...)
+			defer c.Close()
+			if !errors.Is(err, ErrDecryptSnapshot) {
+				t.Fatalf("Load() = %v, want ErrDecryptSnapshot", err)
+			}
+		})
+	}
+}
+
+func TestSnapshotKeyRotation(t *testing.T) {
+	keys := &rotatingKeys{current: 1, keys: map[uint32][]byte{1: bytes.Repeat([]byte{1}, 16)}}
+	old := saveSnapshot(t, WithSnapshotKeyProvider(keys))
+	keys.current, keys.keys[2] = 2, bytes.Repeat([]byte{2}, 16)
+	cur := saveSnapshot(t, WithSnapshotKeyProvider(keys))
+	for name, data := range map[string][]byte{"Old": old, "Current": cur} {
+		c, err := loadSnapshot(data, WithSnapshotKeyProvider(keys))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if n := c.Count(); n != 2 {
+			t.Fatalf("%s: Count() = %d, want 2", name, n)
+		}
+		c.Close()
+	}
+	delete(keys.keys, 1)
+	c, err := loadSnapshot(old, WithSnapshotKeyProvider(keys))
+	defer c.Close()
+	if !errors.Is(err, ErrDecryptSnapshot) {
+		t.Fatalf("Load() with retired key = %v, want ErrDecryptSnapshot", err)
+	}
+}
+
+func TestSnapshotLegacyFormat(t *testing.T) {
+	var buf bytes.Buffer
+	items := map[string]Item{"a": {Object: 1}, "b": {Object: "two"}}
+	if err := gob.NewEncoder(&buf).Encode(&items); err != nil {
+		t.Fatal(err)
+	}
+	c, err := loadSnapshot(buf.Bytes())
+	defer c.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := c.Get("b"); v != "two" {
+		t.Fatalf(`Get("b") = %v, want "two"`, v)
+	}
+	if _, err := ReadSnapshotHeader(bytes.NewReader(buf.Bytes())); !errors.Is(err, ErrNotASnapshot) {
+		t.Fatalf("ReadSnapshotHeader(legacy) = %v, want ErrNotASnapshot", err)
+	}
+}
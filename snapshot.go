@@ -0,0 +1,149 @@
+package minicache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//写时复制的缓存,适合很少更新、大量读取的场景(例如每隔几分钟刷新一次的配置)
+//读操作通过原子指针访问当前版本,完全不加锁;写操作在写锁下复制整个map生成新版本后替换,开销与数据项数量成正比
+//批量修改应使用Update或ReplaceAll,一次只生成一个新版本
+//过期数据项在读取时视为不存在,在下一次写入生成新版本时删除,没有后台gc
+type SnapshotCache struct {
+	mtx               sync.Mutex //串行化写操作
+	current           atomic.Pointer[map[string]Item]
+	defaultExpiration time.Duration
+	version           uint64
+}
+
+//创建写时复制的缓存,defaultExpiration为DefaultExpiration对应的有效期
+func NewSnapshotCache(defaultExpiration time.Duration) *SnapshotCache {
+	sc := &SnapshotCache{defaultExpiration: defaultExpiration}
+	items := map[string]Item{}
+	sc.current.Store(&items)
+	return sc
+}
+
+//Update中对新版本的修改,只在Update的回调内有效
+type SnapshotTx struct {
+	sc    *SnapshotCache
+	items map[string]Item
+}
+
+func (tx *SnapshotTx) Set(k string, v interface{}, d time.Duration) {
+	tx.items[k] = Item{Object: v, Expiration: tx.sc.expiration(d)}
+}
+
+func (tx *SnapshotTx) Delete(k string) {
+	delete(tx.items, k)
+}
+
+//新版本中未过期的数据项
+func (tx *SnapshotTx) Get(k string) (interface{}, bool) {
+	item, found := tx.items[k]
+	if !found || item.IsExpired() {
+		return nil, false
+	}
+	return item.Object, true
+}
+
+func (sc *SnapshotCache) expiration(d time.Duration) int64 {
+	switch d {
+	case NoExpiration:
+		return 0
+	case DefaultExpiration:
+		d = sc.defaultExpiration
+	}
+	if d > 0 {
+		return time.Now().Add(d).UnixNano()
+	}
+	return 0
+}
+
+//复制当前版本并删除其中已过期的数据项,调用方持有写锁
+func (sc *SnapshotCache) clone() map[string]Item {
+	old := *sc.current.Load()
+	now := time.Now().UnixNano()
+	items := make(map[string]Item, len(old)+1)
+	for k, item := range old {
+		if item.Expiration == 0 || now <= item.Expiration {
+			items[k] = item
+		}
+	}
+	return items
+}
+
+func (sc *SnapshotCache) publish(items map[string]Item) {
+	sc.version++
+	sc.current.Store(&items)
+}
+
+//在同一个新版本中执行多个修改,fn返回后新版本对读操作可见
+func (sc *SnapshotCache) Update(fn func(tx *SnapshotTx)) {
+	sc.mtx.Lock()
+	defer sc.mtx.Unlock()
+	tx := &SnapshotTx{sc: sc, items: sc.clone()}
+	fn(tx)
+	items := tx.items
+	tx.items = nil //回调返回后tx不能再修改已发布的版本
+	sc.publish(items)
+}
+
+//用values整体替换缓存内容,不复制旧版本
+func (sc *SnapshotCache) ReplaceAll(values map[string]interface{}, d time.Duration) {
+	e := sc.expiration(d)
+	items := make(map[string]Item, len(values))
+	for k, v := range values {
+		items[k] = Item{Object: v, Expiration: e}
+	}
+	sc.mtx.Lock()
+	defer sc.mtx.Unlock()
+	sc.publish(items)
+}
+
+func (sc *SnapshotCache) Set(k string, v interface{}, d time.Duration) {
+	sc.Update(func(tx *SnapshotTx) {
+		tx.Set(k, v, d)
+	})
+}
+
+func (sc *SnapshotCache) Delete(k string) {
+	sc.Update(func(tx *SnapshotTx) {
+		tx.Delete(k)
+	})
+}
+
+//读取当前版本,不加锁
+func (sc *SnapshotCache) Get(k string) (interface{}, bool) {
+	item, found := (*sc.current.Load())[k]
+	if !found || item.IsExpired() {
+		return nil, false
+	}
+	return item.Object, true
+}
+
+//当前版本中未过期的数据项,返回的map是副本
+func (sc *SnapshotCache) Items() map[string]interface{} {
+	items := *sc.current.Load()
+	now := time.Now().UnixNano()
+	values := make(map[string]interface{}, len(items))
+	for k, item := range items {
+		if item.Expiration == 0 || now <= item.Expiration {
+			values[k] = item.Object
+		}
+	}
+	return values
+}
+
+//当前版本的数据项数量,包括已过期但尚未删除的数据项
+func (sc *SnapshotCache) Count() int {
+	return len(*sc.current.Load())
+}
+
+//已发布的版本数,每次写操作加1
+func (sc *SnapshotCache) Version() uint64 {
+	sc.mtx.Lock()
+	defer sc.mtx.Unlock()
+	return sc.version
+}
@@ -0,0 +1,87 @@
+package minicache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+//读操作看到的要么是Update之前的版本,要么是之后的版本,不会看到一半的修改
+func TestSnapshotUpdateAtomic(t *testing.T) {
+	sc := NewSnapshotCache(NoExpiration)
+	sc.ReplaceAll(map[string]interface{}{"a": 0, "b": 0}, NoExpiration)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				items := sc.Items()
+				if items["a"] != items["b"] {
+					t.Errorf("Items() = %v, saw half of an Update", items)
+					return
+				}
+			}
+		}()
+	}
+	for i := 1; i <= 1000; i++ {
+		sc.Update(func(tx *SnapshotTx) {
+			tx.Set("a", i, NoExpiration)
+			tx.Set("b", i, NoExpiration)
+		})
+	}
+	close(stop)
+	wg.Wait()
+	if v, _ := sc.Get("a"); v != 1000 {
+		t.Fatalf("Get(a) = %v, want 1000", v)
+	}
+}
+
+func TestSnapshotVersion(t *testing.T) {
+	sc := NewSnapshotCache(NoExpiration)
+	if v := sc.Version(); v != 0 {
+		t.Fatalf("Version() of a new cache = %d, want 0", v)
+	}
+	sc.Set("a", 1, NoExpiration)
+	sc.Update(func(tx *SnapshotTx) {
+		tx.Set("b", 2, NoExpiration)
+		tx.Set("c", 3, NoExpiration)
+		tx.Delete("a")
+		if _, found := tx.Get("a"); found {
+			t.Error("tx.Get sees a key deleted in the same Update")
+		}
+		if v, _ := tx.Get("b"); v != 2 {
+			t.Errorf("tx.Get(b) = %v, want 2", v)
+		}
+	})
+	if v := sc.Version(); v != 2 {
+		t.Fatalf("Version() = %d after Set and one Update, want 2", v)
+	}
+	sc.ReplaceAll(map[string]interface{}{"x": 1}, NoExpiration)
+	if v := sc.Version(); v != 3 || sc.Count() != 1 {
+		t.Fatalf("Version() = %d, Count() = %d after ReplaceAll", v, sc.Count())
+	}
+}
+
+func TestSnapshotExpiration(t *testing.T) {
+	sc := NewSnapshotCache(10 * time.Millisecond)
+	sc.Set("a", 1, DefaultExpiration)
+	sc.Set("b", 2, NoExpiration)
+	time.Sleep(20 * time.Millisecond)
+	if _, found := sc.Get("a"); found {
+		t.Fatal("Get returned an expired item")
+	}
+	if n := sc.Count(); n != 2 {
+		t.Fatalf("Count() = %d before the next write, want 2", n)
+	}
+	sc.Set("c", 3, NoExpiration)
+	if n := sc.Count(); n != 2 {
+		t.Fatalf("Count() = %d, the next write did not drop the expired item", n)
+	}
+}
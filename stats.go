@@ -0,0 +1,35 @@
+package minicache
+
+//缓存容量与淘汰统计
+type Stats struct {
+	Entries           int                       //当前数据项数量
+	Cost              int64                     //当前数据项的开销之和
+	Bytes             int64                     //估计的内存占用,仅在配置了WithMaxMemory或WithSizer时统计
+	MaxEntries        int                       //数据项数量上限,0表示不限制
+	MaxCost           int64                     //开销或内存上限,0表示不限制
+	Removals          map[EvictionReason]uint64 //按原因统计的离开缓存的数据项数,包括被覆盖的旧值
+	AdmissionRejected uint64                    //未通过TinyLFU准入而没有保留的写入次数
+	DoorkeeperSkipped uint64                    //新键第一次写入被门卫过滤器跳过的次数
+}
+
+//返回当前的容量与淘汰统计
+func (minic *minicache) Stats() Stats {
+	minic.rwmtx.RLock()
+	defer minic.rwmtx.RUnlock()
+	stats := Stats{
+		Entries:           minic.items.len(),
+		Cost:              minic.totalCost,
+		MaxEntries:        minic.maxEntries,
+		MaxCost:           minic.maxCost,
+		Removals:          make(map[EvictionReason]uint64, reasonCount),
+		AdmissionRejected: minic.admissionRejects,
+		DoorkeeperSkipped: minic.doorkeeperRejects,
+	}
+	if minic.sizer != nil {
+		stats.Bytes = minic.totalCost
+	}
+	for reason, n := range minic.removals {
+		stats.Removals[EvictionReason(reason)] = n
+	}
+	return stats
+}
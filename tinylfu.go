@@ -0,0 +1,73 @@
+package minicache
+
+//计数器的最大值,4位计数足够区分冷热
+const sketchMaxCount = 15
+
+//Count-Min Sketch,估计键在最近一段时间内的访问频率
+//记录的次数达到sampleSize时所有计数减半,使频率估计随时间衰减
+type cmSketch struct {
+	rows       [4][]uint8
+	mask       uint64
+	additions  int
+	sampleSize int
+}
+
+func newCMSketch(capacity int) *cmSketch {
+	width := 16
+	for width < capacity {
+		width <<= 1
+	}
+	s := &cmSketch{mask: uint64(width - 1), sampleSize: lfuDecayFactor * max(capacity, 1)}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+//第i行的下标,由一个64位哈希派生出多个哈希
+func (s *cmSketch) index(h uint64, i int) uint64 {
+	return (h + uint64(i)*(h>>32|1)) & s.mask
+}
+
+func (s *cmSketch) increment(h uint64) {
+	for i := range s.rows {
+		if c := &s.rows[i][s.index(h, i)]; *c < sketchMaxCount {
+			*c++
+		}
+	}
+	if s.additions++; s.additions >= s.sampleSize {
+		s.halve()
+	}
+}
+
+func (s *cmSketch) estimate(h uint64) uint8 {
+	m := uint8(sketchMaxCount)
+	for i := range s.rows {
+		m = min(m, s.rows[i][s.index(h, i)])
+	}
+	return m
+}
+
+func (s *cmSketch) halve() {
+	s.additions = 0
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+}
+
+func (s *cmSketch) reset() {
+	s.additions = 0
+	for i := range s.rows {
+		clear(s.rows[i])
+	}
+}
+
+//缓存已满时用TinyLFU过滤新写入的键:新键的估计访问频率不高于待淘汰键时不写入新键
+//Get的命中和未命中都计入频率,避免只访问一次的键挤掉热点数据,需配合WithMaxEntries使用
+func WithTinyLFUAdmission() Option {
+	return func(minic *Minicache) {
+		minic.admission = true
+	}
+}
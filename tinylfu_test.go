@@ -0,0 +1,39 @@
+package minicache
+
+import "testing"
+
+func TestTinyLFURejectsOneHitKey(t *testing.T) {
+	c := NewMiniCache(0, 0, WithMaxEntries(2), WithTinyLFUAdmission())
+	defer c.Close()
+	c.Set("hot1", 1, 0)
+	c.Set("hot2", 2, 0)
+	for i := 0; i < 10; i++ {
+		c.Get("hot1")
+		c.Get("hot2")
+	}
+	if err := c.Set("once", 3, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := c.Get("once"); found {
+		t.Fatal("one-hit key was admitted over a hot victim")
+	}
+	for _, k := range []string{"hot1", "hot2"} {
+		if _, found := c.Get(k); !found {
+			t.Fatalf("hot key %s was evicted for a one-hit key", k)
+		}
+	}
+	if n := c.Stats().AdmissionRejected; n != 1 {
+		t.Fatalf("AdmissionRejected = %d, want 1", n)
+	}
+	//未命中同样计入频率,经常被请求的新键可以替换热点键
+	for i := 0; i < 30; i++ {
+		c.Get("wanted")
+	}
+	c.Set("wanted", 4, 0)
+	if _, found := c.Get("wanted"); !found {
+		t.Fatal("frequently requested key was rejected")
+	}
+	if n := c.Count(); n != 2 {
+		t.Fatalf("Count() = %d, want 2", n)
+	}
+}
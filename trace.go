@@ -0,0 +1,53 @@
+package minicache
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+//跟踪缓冲区大小,写入方跟不上时丢弃最旧的记录
+const traceBuffer = 256
+
+//在d时长内把涉及键k的每次操作写入w,包括读命中/未命中、写入、删除和有效期变化
+//每行格式为: 时间 操作 键 [ttl=剩余有效期] [source=来源]
+func (minic *minicache) Trace(k string, w io.Writer, d time.Duration) {
+	minic.events.trace(k, w, d)
+}
+
+func (hub *eventHub) trace(k string, w io.Writer, d time.Duration) {
+	sub := hub.subscribe(EventFilter{Key: k}, traceBuffer, DropOldest)
+	go func() {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		for {
+			select {
+			case e := <-sub.Events():
+				writeTrace(w, e)
+			case <-timer.C:
+				sub.Unsubscribe()
+				for e := range sub.Events() {
+					writeTrace(w, e)
+				}
+				if n := sub.Dropped(); n > 0 {
+					fmt.Fprintf(w, "%s trace %s dropped=%d\n", time.Now().Format(time.RFC3339Nano), k, n)
+				}
+				return
+			}
+		}
+	}()
+}
+
+func writeTrace(w io.Writer, e Event) {
+	line := fmt.Sprintf("%s %s %s", e.Time.Format(time.RFC3339Nano), e.Op, e.Key)
+	switch e.Op {
+	case EventSet, EventHit, EventExpiration:
+		if e.Expiration == 0 {
+			line += " ttl=none"
+		} else {
+			line += " ttl=" + time.Duration(e.Expiration-e.Time.UnixNano()).String()
+		}
+		line += " source=" + e.Source.String()
+	}
+	fmt.Fprintln(w, line)
+}
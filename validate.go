@@ -0,0 +1,72 @@
+package minicache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+//快照所在磁盘至少保留的空闲空间
+const minDiskHeadroom = 1 << 20
+
+//校验持久化配置:快照目录可写,磁盘空间足够容纳当前数据,且数据项可被编码
+func (minic *minicache) ValidatePersistence(fileName string) error {
+	sample, count := minic.sampleItem()
+	return minic.validateSnapshot(fileName, sample, count)
+}
+
+//按minic的快照配置检查写入count个与sample大小相近的数据项
+func (minic *minicache) validateSnapshot(fileName string, sample Item, count int) error {
+	dir := filepath.Dir(fileName)
+	f, err := os.CreateTemp(dir, ".minicache-check-*")
+	if err != nil {
+		return fmt.Errorf("Snapshot directory %s is not writable: %v", dir, err)
+	}
+	name := f.Name()
+	f.Close()
+	os.Remove(name)
+
+	//按Save实际使用的压缩和加密配置试编码,同时检查压缩算法已注册、密钥可用
+	fixed, err := minic.trialEncode(map[string]Item{})
+	if err != nil {
+		return fmt.Errorf("Error encoding snapshot: %v", err)
+	}
+	size, err := minic.trialEncode(map[string]Item{"": sample})
+	if err != nil {
+		return fmt.Errorf("Error encoding sample item: %v", err)
+	}
+	if free, ok := diskFree(dir); ok {
+		need := uint64(fixed) + uint64(max(size-fixed, 0))*uint64(count) + minDiskHeadroom
+		if free < need {
+			return fmt.Errorf("Not enough disk space in %s: %d bytes free, about %d bytes needed", dir, free, need)
+		}
+	}
+	return nil
+}
+
+//取任意一个数据项作为编码样本,并返回数据项数量,没有数据项时样本为空字符串
+func (minic *minicache) sampleItem() (Item, int) {
+	minic.rwmtx.RLock()
+	defer minic.rwmtx.RUnlock()
+	sample, n := Item{Object: ""}, minic.items.len()
+	minic.items.each(func(_ string, v Item) bool {
+		sample = v
+		return false
+	})
+	return sample, n
+}
+
+//按缓存的快照配置试编码数据项,返回编码后的字节数
+func (minic *minicache) trialEncode(items map[string]Item) (int64, error) {
+	var n byteCounter
+	err := minic.encode(&n, items)
+	return int64(n), err
+}
+
+//只统计写入的字节数
+type byteCounter int64
+
+func (n *byteCounter) Write(p []byte) (int, error) {
+	*n += byteCounter(len(p))
+	return len(p), nil
+}
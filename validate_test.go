@@ -0,0 +1,72 @@
+package minicache
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestValidatePersistenceUsesSnapshotSettings(t *testing.T) {
+	dir := t.TempDir()
+	for name, tc := range map[string]struct {
+		opt Option
+		ok  bool
+	}{
+		"plain":             {nil, true},
+		"gzip":              {WithSnapshotCompression(CompressionGzip, 0), true},
+		"encrypted":         {WithSnapshotEncryption(make([]byte, 32)), true},
+		"unregistered zstd": {WithSnapshotCompression(CompressionZstd, 0), false},
+		"bad key length":    {WithSnapshotEncryption(make([]byte, 7)), false},
+		"key provider":      {WithSnapshotKeyProvider(failingKeys{}), false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var opts []Option
+			if tc.opt != nil {
+				opts = append(opts, tc.opt)
+			}
+			c := NewMiniCache(0, 0, opts...)
+			defer c.Close()
+			c.Set("k", "v", 0)
+			err := c.ValidatePersistence(filepath.Join(dir, "snap"))
+			if (err == nil) != tc.ok {
+				t.Fatalf("ValidatePersistence() = %v, want ok = %v", err, tc.ok)
+			}
+		})
+	}
+}
+
+type failingKeys struct{}
+
+func (failingKeys) CurrentKey() (uint32, []byte, error) {
+	return 0, nil, errors.New("key service unavailable")
+}
+
+func (failingKeys) Key(uint32) ([]byte, error) {
+	return nil, errors.New("key service unavailable")
+}
+
+func TestAutoSaveValidatesOnCreate(t *testing.T) {
+	dir := t.TempDir()
+	bad := NewMiniCache(0, 0, WithSnapshotEncryption(make([]byte, 7)), WithAutoSave(filepath.Join(dir, "bad"), time.Hour, nil))
+	defer bad.Close()
+	if err := bad.PersistenceError(); err == nil {
+		t.Fatal("PersistenceError() = nil right after creating a cache with an invalid snapshot key")
+	}
+	good := NewMiniCache(0, 0, WithAutoSave(filepath.Join(dir, "good"), time.Millisecond, nil))
+	defer good.Close()
+	good.Set("k", "v", 0)
+	time.Sleep(20 * time.Millisecond)
+	if err := good.PersistenceError(); err != nil {
+		t.Fatalf("PersistenceError() = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "good")); err != nil {
+		t.Fatalf("auto save did not write the snapshot: %v", err)
+	}
+	plain := NewMiniCache(0, 0)
+	defer plain.Close()
+	if err := plain.PersistenceError(); err != nil {
+		t.Fatalf("PersistenceError() without WithAutoSave = %v", err)
+	}
+}
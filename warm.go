@@ -0,0 +1,76 @@
+package minicache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+//预热时使用的加载函数,返回键对应的值和缓存有效期
+type WarmLoader func(ctx context.Context, k string) (interface{}, time.Duration, error)
+
+//并发加载keys并写入缓存,同时执行的加载数不超过concurrency(不大于0时为1),数据项来源为SourceWarm
+//加载或写入失败的键不影响其他键,所有错误合并后返回;ctx取消后不再开始新的加载,并返回ctx.Err()
+func (minic *minicache) Warm(ctx context.Context, keys []string, loader WarmLoader, concurrency int) error {
+	return warm(ctx, keys, loader, concurrency, minic.warmSet)
+}
+
+//用set写入加载的值
+func warm(ctx context.Context, keys []string, loader WarmLoader, concurrency int, set func(k string, v interface{}, d time.Duration) error) error {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	var (
+		wg   sync.WaitGroup
+		mtx  sync.Mutex
+		errs []error
+	)
+	fail := func(err error) {
+		mtx.Lock()
+		errs = append(errs, err)
+		mtx.Unlock()
+	}
+	sem := make(chan struct{}, concurrency)
+	for _, k := range keys {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(k string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			v, d, err := loader(ctx, k)
+			if err == nil {
+				err = set(k, v, d)
+			}
+			if err != nil {
+				fail(fmt.Errorf("warming %s: %w", k, err))
+			}
+		}(k)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func (minic *minicache) warmSet(k string, v interface{}, d time.Duration) error {
+	if closed, err := minic.closed(); closed {
+		return err
+	}
+	if over, err := minic.oversize(k, v); over {
+		return err
+	}
+	minic.rwmtx.Lock()
+	defer minic.writeUnlock()
+	return minic.setFrom(k, v, d, SourceWarm)
+}
@@ -0,0 +1,49 @@
+package minicache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWarm(t *testing.T) {
+	c := NewMiniCache(0, 0)
+	defer c.Close()
+	errBad := errors.New("bad key")
+	loader := func(ctx context.Context, k string) (interface{}, time.Duration, error) {
+		if k == "bad" {
+			return nil, 0, errBad
+		}
+		return "v-" + k, time.Minute, nil
+	}
+	err := c.Warm(context.Background(), []string{"a", "b", "bad", "c"}, loader, 2)
+	if !errors.Is(err, errBad) {
+		t.Fatalf("Warm() error = %v, want %v", err, errBad)
+	}
+	for _, k := range []string{"a", "b", "c"} {
+		meta, found := c.GetWithMeta(k)
+		if !found || meta.Value != "v-"+k {
+			t.Fatalf("GetWithMeta(%q) = %v, %v", k, meta.Value, found)
+		}
+		if meta.Source != SourceWarm {
+			t.Fatalf("Source = %v, want %v", meta.Source, SourceWarm)
+		}
+	}
+	if _, found := c.Get("bad"); found {
+		t.Fatal("failed key was cached")
+	}
+}
+
+func TestWarmCanceled(t *testing.T) {
+	c := NewMiniCache(0, 0)
+	defer c.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	loader := func(ctx context.Context, k string) (interface{}, time.Duration, error) {
+		return k, 0, nil
+	}
+	if err := c.Warm(ctx, []string{"a"}, loader, 1); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Warm() error = %v, want %v", err, context.Canceled)
+	}
+}
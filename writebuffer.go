@@ -0,0 +1,98 @@
+package minicache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+//缓冲的写入,flushed不为nil时表示Wait的标记
+type writeOp struct {
+	k       string
+	v       interface{}
+	d       time.Duration
+	flushed chan struct{}
+}
+
+//每次加锁最多写入的缓冲数量
+const writeBatchSize = 256
+
+//开启异步写入,Set把写入放入容量为size的缓冲区后立即返回,由后台goroutine分批加锁写入
+//缓冲区满时Set阻塞;写入在之后一小段时间内才可见,需要读到自己的写入时先调用Wait
+//异步写入的失败(例如拒绝模式下缓存已满)不会返回给调用方;只有Set使用缓冲区,其他写操作仍同步执行
+func WithWriteBuffer(size int) Option {
+	return func(minic *Minicache) {
+		if size > 0 {
+			minic.writes = make(chan writeOp, size)
+		}
+	}
+}
+
+//放入缓冲区,开始关闭之后按关闭策略返回,放入的写入一定在Close返回前完成
+func (minic *minicache) enqueue(op writeOp) error {
+	minic.writesMtx.RLock()
+	if atomic.LoadInt32(&minic.state) == stateOpen {
+		minic.writes <- op
+		minic.writesMtx.RUnlock()
+		return nil
+	}
+	minic.writesMtx.RUnlock()
+	_, err := minic.closed()
+	return err
+}
+
+//等待调用之前放入缓冲区的写入全部完成,未开启异步写入或缓存已关闭时直接返回
+func (minic *minicache) Wait() {
+	if minic.writes == nil {
+		return
+	}
+	flushed := make(chan struct{})
+	select {
+	case minic.writes <- writeOp{flushed: flushed}:
+	case <-minic.done:
+		return
+	}
+	select {
+	case <-flushed:
+	case <-minic.done:
+	}
+}
+
+//后台写入缓冲区中的数据,Close在关闭前等待缓冲区写完,关闭后直接退出
+func (minic *minicache) drainWrites() {
+	defer minic.background.Done()
+	batch := make([]writeOp, 0, writeBatchSize)
+	for {
+		select {
+		case op := <-minic.writes:
+			batch = append(batch[:0], op)
+		case <-minic.done:
+			return
+		}
+	more:
+		for len(batch) < writeBatchSize {
+			select {
+			case op := <-minic.writes:
+				batch = append(batch, op)
+			default:
+				break more
+			}
+		}
+		minic.applyWrites(batch)
+	}
+}
+
+//在一次加锁中执行一批写入,再通知其中的Wait
+func (minic *minicache) applyWrites(batch []writeOp) {
+	minic.rwmtx.Lock()
+	for _, op := range batch {
+		if op.flushed == nil {
+			minic.set(op.k, op.v, op.d)
+		}
+	}
+	minic.writeUnlock()
+	for _, op := range batch {
+		if op.flushed != nil {
+			close(op.flushed)
+		}
+	}
+}
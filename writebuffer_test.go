@@ -0,0 +1,60 @@
+package minicache
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWriteBufferWait(t *testing.T) {
+	c := NewMiniCache(0, 0, WithWriteBuffer(16))
+	defer c.Close()
+	for i := 0; i < 100; i++ {
+		if err := c.Set(fmt.Sprint("k", i), i, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.Wait()
+	if n := c.Count(); n != 100 {
+		t.Fatalf("Count() after Wait = %d, want 100", n)
+	}
+}
+
+//Close返回时所有返回nil的写入都已完成,之后的写入被拒绝且不会再写入
+func TestWriteBufferClose(t *testing.T) {
+	for round := 0; round < 20; round++ {
+		c := NewMiniCache(0, 0, WithWriteBuffer(4))
+		var accepted int64
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := 0; i < 200; i++ {
+					err := c.Set(fmt.Sprint(g, "-", i), i, 0)
+					switch {
+					case err == nil:
+						atomic.AddInt64(&accepted, 1)
+					case !errors.Is(err, ErrCacheClosed):
+						t.Errorf("Set = %v", err)
+					}
+					if i%50 == 0 {
+						c.Wait()
+					}
+				}
+			}(g)
+		}
+		c.Close()
+		closedCount := c.Count()
+		wg.Wait()
+		if n := c.Count(); n != closedCount {
+			t.Fatalf("Count() changed from %d to %d after Close", closedCount, n)
+		}
+		if n := atomic.LoadInt64(&accepted); int64(closedCount) != n {
+			t.Fatalf("Count() = %d after Close, %d writes returned nil", closedCount, n)
+		}
+		c.Wait()
+	}
+}